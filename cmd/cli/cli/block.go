@@ -17,8 +17,12 @@ type TransactionView struct {
 	Sender    string  `json:"sender"`
 	Receiver  string  `json:"receiver"`
 	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
 	Timestamp int64   `json:"timestamp"`
 	Signature string  `json:"signature"`
+
+	Type        string `json:"type,omitempty"`
+	ValidatorId string `json:"validator_id,omitempty"`
 }
 
 type BlockView struct {
@@ -26,12 +30,24 @@ type BlockView struct {
 	PreviousBlockHash string            `json:"previous_block_hash"`
 	CurrentBlockHash  string            `json:"current_block_hash"`
 	Height            uint64            `json:"height"`
+	ProposerId        string            `json:"proposer_id"`
+	CertifiedBy       []string          `json:"certified_by"`
+	Difficulty        uint32            `json:"difficulty,omitempty"`
+	Nonce             uint64            `json:"nonce,omitempty"`
+	Evidence          []EvidenceView    `json:"evidence,omitempty"`
 	Transactions      []TransactionView `json:"transactions"`
 }
 
+type EvidenceView struct {
+	Type   string `json:"type"`
+	NodeId string `json:"node_id"`
+	Height uint64 `json:"height"`
+	Round  uint32 `json:"round"`
+}
+
 func GetCurrentBlockHeightCLI() {
 	getCurrentBlockHeightCmd := flag.NewFlagSet("get-current-block-height", flag.ExitOnError)
-	node := getCurrentBlockHeightCmd.String("node", leaderAddress, "Input node target")
+	node := getCurrentBlockHeightCmd.String("node", defaultNodeAddress, "Input node target")
 
 	getCurrentBlockHeightCmd.Parse(os.Args[2:])
 
@@ -57,7 +73,8 @@ func GetCurrentBlockHeightCLI() {
 func GetBlockCLI() {
 	getBlockCmd := flag.NewFlagSet("get-block", flag.ExitOnError)
 	blockHeight := getBlockCmd.Uint64("block-height", 0, "Input block height")
-	node := getBlockCmd.String("node", leaderAddress, "Input node target")
+	blockHash := getBlockCmd.String("hash", "", "Input block hash (base58)")
+	node := getBlockCmd.String("node", defaultNodeAddress, "Input node target")
 
 	getBlockCmd.Parse(os.Args[2:])
 
@@ -67,8 +84,11 @@ func GetBlockCLI() {
 	}
 	fmt.Printf("Connect node `%s`\n", *node)
 
-	if *blockHeight <= 0 {
-		log.Fatalf("Error: blockHeight is required and larger than 0")
+	if *blockHash == "" && *blockHeight <= 0 {
+		log.Fatalf("Error: blockHeight larger than 0 or hash is required")
+	}
+	if *blockHash != "" && *blockHeight > 0 {
+		log.Fatalf("Error: use either blockHeight or hash")
 	}
 
 	client, err := GetClient(*node)
@@ -76,11 +96,22 @@ func GetBlockCLI() {
 		log.Fatalf("Error: Cant connect node: %s", *node)
 	}
 
-	block, err := client.GetBlock(context.Background(), &pb.BlockHeight{
-		Height: *blockHeight,
-	})
+	var block *pb.Block
+	if *blockHash != "" {
+		hash, decodeErr := util.Base58Decode(*blockHash)
+		if decodeErr != nil {
+			log.Fatalf("Error: invalid hash '%s': %v", *blockHash, decodeErr)
+		}
+		block, err = client.GetBlockByHash(context.Background(), &pb.BlockHash{
+			Hash: hash,
+		})
+	} else {
+		block, err = client.GetBlock(context.Background(), &pb.BlockHeight{
+			Height: *blockHeight,
+		})
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "leveldb: not found") {
+		if strings.Contains(err.Error(), "not found") {
 			fmt.Println("\033[1;31mBlock not found\033[0m")
 			return
 		}
@@ -94,17 +125,48 @@ func GetBlockCLI() {
 			Sender:    string(tx.Sender),
 			Receiver:  string(tx.Receiver),
 			Amount:    tx.Amount,
+			Fee:       tx.Fee,
 			Timestamp: tx.Timestamp,
 			Signature: util.Base58Encode(tx.Signature),
+
+			Type:        tx.Type,
+			ValidatorId: tx.ValidatorId,
 		}
 		transactionViews = append(transactionViews, txView)
 	}
 
+	// Validators whose signature is in the quorum certificate
+	certifiedBy := []string{}
+	if block.Certificate != nil {
+		for _, vote := range block.Certificate.Votes {
+			certifiedBy = append(certifiedBy, vote.NodeId)
+		}
+		for _, commit := range block.Certificate.Commits {
+			certifiedBy = append(certifiedBy, commit.NodeId)
+		}
+	}
+
+	// Validators slashed for equivocation
+	var evidenceViews []EvidenceView
+	for _, evidence := range block.Evidence {
+		evidenceViews = append(evidenceViews, EvidenceView{
+			Type:   evidence.Type,
+			NodeId: evidence.NodeId,
+			Height: evidence.Height,
+			Round:  evidence.Round,
+		})
+	}
+
 	blockView := BlockView{
 		MerkleRootHash:    util.Base58Encode(block.MerkleRootHash),
 		PreviousBlockHash: util.Base58Encode(block.PreviousBlockHash),
 		CurrentBlockHash:  util.Base58Encode(block.CurrentBlockHash),
 		Height:            block.Height,
+		ProposerId:        block.ProposerId,
+		CertifiedBy:       certifiedBy,
+		Difficulty:        block.Difficulty,
+		Nonce:             block.Nonce,
+		Evidence:          evidenceViews,
 		Transactions:      transactionViews,
 	}
 
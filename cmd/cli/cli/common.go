@@ -1,17 +1,21 @@
 package cli
 
 import (
+	"fmt"
 	"go-blockchain-ber1/pkg/p2p/pb"
+	"os"
+	"strings"
 	"sync"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-const leaderAddress = "localhost:50051"
+const defaultNodeAddress = "localhost:50051"
 
-var nodes = []string{leaderAddress, "localhost:50052", "localhost:50053"}
-var nodeOrder = []string{"node1", "node2", "node3"}
+// Bigger networks can override the node targets: NODES=localhost:50051,localhost:50052,...
+var nodes = getNodes()
+var nodeOrder = getNodeOrder()
 var addressNodeMap map[string]string = make(map[string]string)
 
 var infoNodes map[string]string = make(map[string]string)
@@ -27,3 +31,20 @@ func GetClient(targetNode string) (pb.BlockchainClient, error) {
 
 	return client, nil
 }
+
+func getNodes() []string {
+	if os.Getenv("NODES") != "" {
+		return strings.Split(os.Getenv("NODES"), ",")
+	}
+
+	return []string{defaultNodeAddress, "localhost:50052", "localhost:50053", "localhost:50054"}
+}
+
+func getNodeOrder() []string {
+	var order []string
+	for i := range nodes {
+		order = append(order, fmt.Sprintf("node%d", i+1))
+	}
+
+	return order
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"go-blockchain-ber1/pkg/storage"
+	"log"
+	"os"
+)
+
+// MigrateDatabaseCLI converts the database of a stopped node to the current key schema
+func MigrateDatabaseCLI() {
+	migrateDatabaseCmd := flag.NewFlagSet("migrate-db", flag.ExitOnError)
+	dataDir := migrateDatabaseCmd.String("data-dir", "data", "Input data directory of the stopped node (env DATA_DIR of the node)")
+
+	migrateDatabaseCmd.Parse(os.Args[2:])
+
+	if _, err := os.Stat(*dataDir); err != nil {
+		log.Fatalf("Error: data directory not found: %s", *dataDir)
+	}
+
+	db, err := storage.NewLevelDB(*dataDir)
+	if err != nil {
+		log.Fatalf("Error: Cant open database (is the node stopped?): %v", err)
+	}
+	defer db.Close()
+
+	version, err := storage.GetSchemaVersion(db)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if version == storage.SchemaVersion {
+		fmt.Printf("Database already uses key schema %d\n", storage.SchemaVersion)
+		return
+	}
+
+	converted, err := storage.MigrateSchema(db)
+	if err != nil {
+		log.Fatalf("Error: Migrate database failed: %v", err)
+	}
+
+	fmt.Printf("Migrated %d blocks from key schema %d to %d in: %s\n", converted, version, storage.SchemaVersion, *dataDir)
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+func formatMempoolEntry(entry *pb.MempoolEntry) []any {
+	tx := entry.Transaction
+	age := time.Since(time.Unix(tx.Timestamp, 0)).Round(time.Second)
+
+	return []any{util.Base58Encode(entry.Hash), string(tx.Sender), tx.Nonce, tx.Amount, tx.Fee, age}
+}
+
+func mempoolListCLI() {
+	mempoolListCmd := flag.NewFlagSet("mempool list", flag.ExitOnError)
+	node := mempoolListCmd.String("node", defaultNodeAddress, "Input node target")
+	sender := mempoolListCmd.String("sender", "", "Optional: only transactions of this sender address")
+	offset := mempoolListCmd.Uint("offset", 0, "Input page offset")
+	limit := mempoolListCmd.Uint("limit", 50, "Input page size")
+
+	mempoolListCmd.Parse(os.Args[3:])
+
+	isNodeExist := slices.Contains(nodes, *node)
+	if !isNodeExist {
+		log.Fatalf("invalid node '%s'; allowed nodes: %v", *node, nodes)
+	}
+	fmt.Printf("Connect node `%s`\n", *node)
+
+	client, err := GetClient(*node)
+	if err != nil {
+		log.Fatalf("Error: Cant connect node: %s", *node)
+	}
+
+	res, err := client.GetMempool(context.Background(), &pb.GetMempoolRequest{
+		Offset: uint32(*offset),
+		Limit:  uint32(*limit),
+		Sender: []byte(*sender),
+	})
+	if err != nil {
+		log.Fatalf("Error: Get Mempool Failed: %v", err)
+	}
+
+	fmt.Printf("Pending Transactions: %d (showing %d from offset %d)\n", res.Total, len(res.Entries), *offset)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "HASH\tSENDER\tNONCE\tAMOUNT\tFEE\tAGE")
+	for _, entry := range res.Entries {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%v\t%v\t%s\n", formatMempoolEntry(entry)...)
+	}
+	writer.Flush()
+}
+
+func mempoolWatchCLI() {
+	mempoolWatchCmd := flag.NewFlagSet("mempool watch", flag.ExitOnError)
+	node := mempoolWatchCmd.String("node", defaultNodeAddress, "Input node target")
+
+	mempoolWatchCmd.Parse(os.Args[3:])
+
+	isNodeExist := slices.Contains(nodes, *node)
+	if !isNodeExist {
+		log.Fatalf("invalid node '%s'; allowed nodes: %v", *node, nodes)
+	}
+	fmt.Printf("Connect node `%s`\n", *node)
+
+	client, err := GetClient(*node)
+	if err != nil {
+		log.Fatalf("Error: Cant connect node: %s", *node)
+	}
+
+	stream, err := client.SubscribeMempool(context.Background(), nil)
+	if err != nil {
+		log.Fatalf("Error: Subscribe Mempool Failed: %v", err)
+	}
+
+	fmt.Println("Watching mempool events...")
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			log.Fatalf("Error: Mempool stream closed: %v", err)
+		}
+
+		row := append([]any{event.Type}, formatMempoolEntry(event.Entry)...)
+		fmt.Printf("%-8s hash=%s sender=%s nonce=%d amount=%v fee=%v age=%s\n", row...)
+	}
+}
+
+func MempoolCLI() {
+	if len(os.Args) < 3 {
+		log.Fatalf("Error: mempool command is required (list, watch)")
+	}
+
+	switch os.Args[2] {
+	case "list":
+		mempoolListCLI()
+	case "watch":
+		mempoolWatchCLI()
+	default:
+		fmt.Println("Unknown mempool CLI")
+	}
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"log"
+	"os"
+	"slices"
+)
+
+// stakeCLI sends a STAKE or UNSTAKE transaction of the sender for the validator
+func stakeCLI(command string, txType string) {
+	stakeCmd := flag.NewFlagSet(command, flag.ExitOnError)
+	sender := stakeCmd.String("sender", "", "Input sender address (owner of the stake)")
+	validatorId := stakeCmd.String("validator-id", "", "Input validator id")
+	validatorAddress := stakeCmd.String("validator-address", "", "Input validator address host:port (required for a new validator)")
+	amount := stakeCmd.Float64("amount", 0, "Input amount")
+	fee := stakeCmd.Float64("fee", 0, "Input fee")
+	node := stakeCmd.String("node", defaultNodeAddress, "Input node target")
+
+	stakeCmd.Parse(os.Args[2:])
+
+	if *sender == "" {
+		log.Fatalf("Error: sender is required")
+	}
+	if *validatorId == "" {
+		log.Fatalf("Error: validator-id is required")
+	}
+	if *amount <= 0 {
+		log.Fatalf("Error: amount must be greater than 0")
+	}
+	if *fee < 0 {
+		log.Fatalf("Error: fee must not be negative")
+	}
+
+	isNodeExist := slices.Contains(nodes, *node)
+	if !isNodeExist {
+		log.Fatalf("invalid node '%s'; allowed nodes: %v", *node, nodes)
+	}
+	fmt.Printf("Connect node `%s`\n", *node)
+
+	senderData, err := util.FindUserByAddress(*sender)
+	if err != nil {
+		log.Fatalf("Error: Sender not found")
+	}
+
+	client, err := GetClient(*node)
+	if err != nil {
+		log.Fatalf("Error: Cant connect node: %s", *node)
+	}
+
+	accountNonce, err := client.GetAccountNonce(context.Background(), &pb.Account{Address: []byte(*sender)})
+	if err != nil {
+		log.Fatalf("Error: Get Nonce Failed: %v", err)
+	}
+
+	// The validator signs with the key of the sender
+	tx := blockchain.NewTransaction([]byte(*sender), nil, *amount, *fee, accountNonce.PendingNonce+1)
+	tx.Type = txType
+	tx.ValidatorId = *validatorId
+	if txType == blockchain.TX_STAKE {
+		tx.ValidatorAddress = *validatorAddress
+	}
+	signAndSendTransaction(client, senderData, tx)
+
+	fmt.Printf("Sent %s transaction with nonce: %d\n", txType, tx.Nonce)
+}
+
+func StakeCLI() {
+	stakeCLI("stake", blockchain.TX_STAKE)
+}
+
+func UnstakeCLI() {
+	stakeCLI("unstake", blockchain.TX_UNSTAKE)
+}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
 	"go-blockchain-ber1/pkg/types"
 	"go-blockchain-ber1/pkg/util"
 	"go-blockchain-ber1/pkg/wallet"
@@ -19,7 +20,8 @@ func SendTransactionCLI() {
 	sender := sendTransactionCmd.String("sender", "", "Input sender address")
 	receiver := sendTransactionCmd.String("receiver", "", "Input receiver address")
 	amount := sendTransactionCmd.Float64("amount", 0, "Input amount")
-	node := sendTransactionCmd.String("node", leaderAddress, "Input node target")
+	fee := sendTransactionCmd.Float64("fee", 0, "Input fee")
+	node := sendTransactionCmd.String("node", defaultNodeAddress, "Input node target")
 
 	sendTransactionCmd.Parse(os.Args[2:])
 
@@ -32,6 +34,9 @@ func SendTransactionCLI() {
 	if *amount <= 0 {
 		log.Fatalf("Error: amount must be greater than 0")
 	}
+	if *fee < 0 {
+		log.Fatalf("Error: fee must not be negative")
+	}
 
 	isNodeExist := slices.Contains(nodes, *node)
 	if !isNodeExist {
@@ -58,22 +63,130 @@ func SendTransactionCLI() {
 		log.Fatalf("Error: Receiver not found")
 	}
 
+	client, err := GetClient(*node)
+	if err != nil {
+		log.Fatalf("Error: Cant connect node: %s", *node)
+	}
+
+	// Next nonce comes after the pending transactions of the sender
+	accountNonce, err := client.GetAccountNonce(context.Background(), &pb.Account{Address: []byte(*sender)})
+	if err != nil {
+		log.Fatalf("Error: Get Nonce Failed: %v", err)
+	}
+
 	// Create transaction
+	tx := blockchain.NewTransaction([]byte(*sender), []byte(*receiver), *amount, *fee, accountNonce.PendingNonce+1)
+	signAndSendTransaction(client, senderData, tx)
+
+	fmt.Printf("Sent transaction with nonce: %d\n", tx.Nonce)
+}
+
+func signAndSendTransaction(client pb.BlockchainClient, senderData *types.UserData, tx *blockchain.Transaction) {
 	privKey, _ := util.DecodePrivateKey(senderData.PrivateKey)
-	tx := blockchain.NewTransaction([]byte(*sender), []byte(*receiver), *amount)
 	wallet.SignTransaction(tx, privKey)
 
 	publicKey := util.EncodePublicKey(privKey)
 
+	pbTx := util.ConvertToPbTransaction(tx)
+	pbTx.PublicKey = []byte(publicKey)
+
+	if _, err := client.SendTransaction(context.Background(), pbTx); err != nil {
+		log.Fatalf("Error: Send Transaction Failed: %v", err)
+	}
+}
+
+// Pending transactions of a sender read per GetMempool call, the node clamps a larger limit
+const mempoolPageLimit = 1000
+
+// findPendingTransaction pages through the pending transactions of the sender until one has the nonce
+func findPendingTransaction(client pb.BlockchainClient, sender string, nonce uint64) *pb.Transaction {
+	for offset := uint32(0); ; offset += mempoolPageLimit {
+		res, err := client.GetMempool(context.Background(), &pb.GetMempoolRequest{
+			Sender: []byte(sender),
+			Offset: offset,
+			Limit:  mempoolPageLimit,
+		})
+		if err != nil {
+			log.Fatalf("Error: Get Mempool Failed: %v", err)
+		}
+
+		for _, entry := range res.Entries {
+			if entry.Transaction.Nonce == nonce {
+				return entry.Transaction
+			}
+		}
+
+		// A short page is the last one
+		if len(res.Entries) < mempoolPageLimit {
+			break
+		}
+	}
+
+	log.Fatalf("Error: No pending transaction of sender with nonce %d", nonce)
+	return nil
+}
+
+// replaceTransactionCLI re-sends the pending transaction (sender, nonce) with a higher fee.
+// isCancel turns it into a zero-amount transfer to the sender itself.
+func replaceTransactionCLI(command string, isCancel bool) {
+	replaceTransactionCmd := flag.NewFlagSet(command, flag.ExitOnError)
+	sender := replaceTransactionCmd.String("sender", "", "Input sender address")
+	nonce := replaceTransactionCmd.Uint64("nonce", 0, "Input nonce of the pending transaction")
+	fee := replaceTransactionCmd.Float64("fee", 0, "Input new fee (must be higher than the pending one)")
+	node := replaceTransactionCmd.String("node", defaultNodeAddress, "Input node target")
+
+	replaceTransactionCmd.Parse(os.Args[2:])
+
+	if *sender == "" {
+		log.Fatalf("Error: sender is required")
+	}
+	if *nonce == 0 {
+		log.Fatalf("Error: nonce is required")
+	}
+
+	isNodeExist := slices.Contains(nodes, *node)
+	if !isNodeExist {
+		log.Fatalf("invalid node '%s'; allowed nodes: %v", *node, nodes)
+	}
+	fmt.Printf("Connect node `%s`\n", *node)
+
+	senderData, err := util.FindUserByAddress(*sender)
+	if err != nil {
+		log.Fatalf("Error: Sender not found")
+	}
+
 	client, err := GetClient(*node)
 	if err != nil {
 		log.Fatalf("Error: Cant connect node: %s", *node)
 	}
 
-	pbTx := util.ConvertToPbTransaction(tx)
-	pbTx.PublicKey = []byte(publicKey)
+	pendingTx := findPendingTransaction(client, *sender, *nonce)
+	if *fee <= pendingTx.Fee {
+		log.Fatalf("Error: fee must be higher than the pending fee %v", pendingTx.Fee)
+	}
 
-	if _, err := client.SendTransaction(context.Background(), pbTx); err != nil {
-		log.Fatalf("Error: Send Transaction Failed: %v", err)
+	receiver := pendingTx.Receiver
+	amount := pendingTx.Amount
+	if isCancel {
+		receiver = []byte(*sender)
+		amount = 0
 	}
+
+	tx := blockchain.NewTransaction([]byte(*sender), receiver, amount, *fee, *nonce)
+	if !isCancel {
+		tx.Type = pendingTx.Type
+		tx.ValidatorId = pendingTx.ValidatorId
+		tx.ValidatorAddress = pendingTx.ValidatorAddress
+	}
+	signAndSendTransaction(client, senderData, tx)
+
+	fmt.Printf("Replaced pending transaction with nonce: %d\n", *nonce)
+}
+
+func SpeedUpTransactionCLI() {
+	replaceTransactionCLI("speed-up", false)
+}
+
+func CancelTransactionCLI() {
+	replaceTransactionCLI("cancel", true)
 }
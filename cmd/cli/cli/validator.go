@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const composeTemplate = `version: '3.8'
+
+# Generated by: go run ./cmd/cli/main.go create-validators --count {{len .Validators}}
+
+x-env: &commonEnv
+  LEVEL_DEBUG: true
+  GENESIS_FILE: /app/genesis.json
+
+x-build: &commonBuild
+  context: .
+  dockerfile: Dockerfile
+
+services:
+{{- range $i, $validator := .Validators}}
+  {{$validator.Id}}:
+    build:
+      <<: *commonBuild
+    environment:
+      NODE_ID: {{$validator.Id}}
+      <<: *commonEnv
+    ports:
+      - "{{add 50051 $i}}:50051"
+    volumes:
+      - {{$validator.Id}}_data:/app/data
+      - ./{{$.GenesisFile}}:/app/genesis.json:ro
+      - ./{{$.KeysDir}}/{{$validator.Id}}.json:/app/validator_keys.json:ro
+{{- if ne $i 0}}
+    depends_on:
+      - {{(index $.Validators 0).Id}}
+{{- end}}
+    restart: on-failure:3
+{{- end}}
+
+volumes:
+{{- range .Validators}}
+  {{.Id}}_data:
+{{- end}}
+`
+
+func writeJSONFile(filePath string, data any, perm os.FileMode) error {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, jsonBytes, perm)
+}
+
+// createValidators writes the genesis and, in keysDir, one keys file per validator holding only its own key
+func createValidators(count int, chainId string, engine string, genesisFile string, keysDir string, composeFile string) error {
+	genesis := types.Genesis{ChainId: chainId, ConsensusEngine: engine}
+	var validatorKeys []types.ValidatorKey
+
+	for i := 1; i <= count; i++ {
+		privKey, err := wallet.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+
+		id := fmt.Sprintf("node%d", i)
+		genesis.Validators = append(genesis.Validators, types.Validator{
+			Id:        id,
+			Address:   id + ":50051",
+			PublicKey: util.EncodePublicKey(privKey),
+		})
+		validatorKeys = append(validatorKeys, types.ValidatorKey{
+			Id:         id,
+			PrivateKey: util.Base58CheckEncode(privKey.D.Bytes()),
+		})
+	}
+
+	if err := writeJSONFile(genesisFile, genesis, 0644); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(keysDir, 0700); err != nil {
+		return err
+	}
+	for _, validatorKey := range validatorKeys {
+		if err := writeJSONFile(filepath.Join(keysDir, validatorKey.Id+".json"), []types.ValidatorKey{validatorKey}, 0600); err != nil {
+			return err
+		}
+	}
+
+	if composeFile == "" {
+		return nil
+	}
+
+	tmpl, err := template.New("compose").Funcs(template.FuncMap{
+		"add": func(a int, b int) int { return a + b },
+	}).Parse(composeTemplate)
+	if err != nil {
+		return err
+	}
+
+	var compose strings.Builder
+	if err := tmpl.Execute(&compose, map[string]any{
+		"Validators":  genesis.Validators,
+		"GenesisFile": genesisFile,
+		"KeysDir":     keysDir,
+	}); err != nil {
+		return err
+	}
+
+	return os.WriteFile(composeFile, []byte(compose.String()), 0644)
+}
+
+func CreateValidatorsCLI() {
+	createValidatorsCmd := flag.NewFlagSet("create-validators", flag.ExitOnError)
+	count := createValidatorsCmd.Int("count", 4, "Input number of validators")
+	chainId := createValidatorsCmd.String("chain-id", "ber1", "Input chain id")
+	engine := createValidatorsCmd.String("engine", "", "Optional: consensus engine of the network (vote, pbft, pow)")
+	genesisFile := createValidatorsCmd.String("genesis", "genesis.json", "Output genesis file (validator ids, addresses and public keys)")
+	keysDir := createValidatorsCmd.String("keys-dir", "validator_keys", "Output directory of the validator private keys, one <id>.json file per validator")
+	composeFile := createValidatorsCmd.String("compose", "", "Optional: output docker compose file for the network")
+
+	createValidatorsCmd.Parse(os.Args[2:])
+
+	if *count <= 0 {
+		log.Fatalf("Error: count must be greater than 0")
+	}
+
+	if err := createValidators(*count, *chainId, *engine, *genesisFile, *keysDir, *composeFile); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	fmt.Printf("Created %d validators in: %s, keys in: %s\n", *count, *genesisFile, *keysDir)
+}
@@ -10,14 +10,28 @@ func main() {
 	switch os.Args[1] {
 	case "create-user":
 		cli.CreateUserCLI()
+	case "create-validators":
+		cli.CreateValidatorsCLI()
 	case "send-transaction":
 		cli.SendTransactionCLI()
+	case "speed-up":
+		cli.SpeedUpTransactionCLI()
+	case "cancel":
+		cli.CancelTransactionCLI()
+	case "stake":
+		cli.StakeCLI()
+	case "unstake":
+		cli.UnstakeCLI()
 	case "get-block":
 		cli.GetBlockCLI()
 	case "get-current-block-height":
 		cli.GetCurrentBlockHeightCLI()
+	case "mempool":
+		cli.MempoolCLI()
 	case "monitor-node":
 		cli.MonitorNodesCLI()
+	case "migrate-db":
+		cli.MigrateDatabaseCLI()
 	default:
 		fmt.Println("Unknown CLI")
 	}
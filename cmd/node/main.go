@@ -1,25 +1,41 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"go-blockchain-ber1/pkg/blockchain"
 	"go-blockchain-ber1/pkg/config"
 	"go-blockchain-ber1/pkg/consensus"
 	"go-blockchain-ber1/pkg/node"
 	"go-blockchain-ber1/pkg/p2p"
 	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"log"
 	"log/slog"
+	"net"
 	"os"
 	"strings"
 )
 
 func main() {
-	const addressPort = ":50051"
+	const defaultAddressPort = ":50051"
 
 	// Get Eviroment
 	nodeId := os.Getenv("NODE_ID")
-	leaderAddress := os.Getenv("LEADER") + addressPort
-	isLeader := strings.Split(leaderAddress, ":")[0] == nodeId
-	peers := strings.Split(os.Getenv("PEERS"), ",")
+	genesisFile := os.Getenv("GENESIS_FILE")
+	if genesisFile == "" {
+		genesisFile = "genesis.json"
+	}
+	validatorKeysFile := os.Getenv("VALIDATOR_KEYS_FILE")
+	if validatorKeysFile == "" {
+		validatorKeysFile = "validator_keys.json"
+	}
+	dataDir := os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	consensusEngine := os.Getenv("CONSENSUS_ENGINE")
+	listenPort := os.Getenv("PORT")
 	isLevelDebug := os.Getenv("LEVEL_DEBUG") == "true"
 
 	// Config
@@ -28,28 +44,108 @@ func main() {
 	// START
 	slog.Info("===============START=================")
 
+	// Validator set
+	genesis, err := config.LoadGenesis(genesisFile)
+	if err != nil {
+		log.Fatalf("Load genesis failed: %v", err)
+	}
+	validatorSet := consensus.NewValidatorSet(genesis.Validators)
+
+	// Consensus engine of the network, env CONSENSUS_ENGINE overrides it to experiment
+	engineName := genesis.ConsensusEngine
+	if consensusEngine != "" {
+		engineName = consensusEngine
+	}
+
+	// Signing key of this validator, proof of work signs nothing and runs without one
+	var privateKey *ecdsa.PrivateKey
+	if engineName != consensus.ENGINE_POW {
+		privateKey, err = config.LoadValidatorKey(os.Getenv("VALIDATOR_KEY"), validatorKeysFile, nodeId)
+		if err != nil {
+			log.Fatalf("Load validator key failed: %v", err)
+		}
+		if validator, ok := validatorSet.Get(nodeId); ok && validator.PublicKey != util.EncodePublicKey(privateKey) {
+			log.Fatalf("Validator key does not match the public key of '%s' in genesis", nodeId)
+		}
+	}
+
+	// Peers are the other validators unless PEERS is set
+	var peers []string
+	if os.Getenv("PEERS") != "" {
+		peers = strings.Split(os.Getenv("PEERS"), ",")
+	} else {
+		for _, validator := range validatorSet.Validators() {
+			if validator.Id != nodeId {
+				peers = append(peers, validator.Address)
+			}
+		}
+	}
+
 	// Init Database
-	db := storage.NewLevelDB("data")
+	db, err := storage.NewLevelDB(dataDir)
+	if err != nil {
+		log.Fatalf("Open LevelDB failed: %v", err)
+	}
 	defer db.Close()
 
 	// Init Block Database
 	blockDB := storage.NewBlockDB(db)
-	blockDB.Init()
+	if err := blockDB.Init(genesis); err != nil {
+		log.Fatalf("Init block database failed: %v", err)
+	}
 
-	// Init Peer Manager
-	peerManager := p2p.NewPeerManager(leaderAddress)
-	peerManager.AddPeers(peers)
+	// Validators joining by staking are in the stake table, not in genesis
+	validator, isValidator := validatorSet.Get(nodeId)
+	if stake, err := blockDB.GetStake(nodeId); !isValidator && err == nil && stake != nil {
+		if privateKey != nil && stake.Validator.PublicKey != util.EncodePublicKey(privateKey) {
+			log.Fatalf("Validator key does not match the public key staked for '%s'", nodeId)
+		}
+		validator, isValidator = stake.Validator, true
+	}
+
+	// Listen on the port of this validator address, env PORT overrides it (a new validator before its stake is synced)
+	addressPort := defaultAddressPort
+	if listenPort != "" {
+		addressPort = ":" + listenPort
+	} else if isValidator {
+		if _, port, err := net.SplitHostPort(validator.Address); err == nil {
+			addressPort = ":" + port
+		}
+	}
+	if !isValidator {
+		slog.Warn("This node is not in the validator set", "nodeId", nodeId)
+	}
 
 	//
-	memPool := blockchain.NewMemPool()
-	consensus := consensus.NewConsensus(blockDB)
+	memPoolDB := storage.NewMemPoolDB(db)
+	memPool := blockchain.NewMemPool(memPoolDB)
+
+	// Proposals and votes of the rounds in flight, replayed by the consensus engine at restart
+	walDB := storage.NewWalDB(db)
+
+	engine, err := consensus.NewEngine(engineName, genesis, blockDB, walDB, validatorSet, nodeId, privateKey)
+	if err != nil {
+		log.Fatalf("Init consensus engine failed: %v", err)
+	}
+
+	// Init Peer Manager
+	peerManager := p2p.NewPeerManager(validatorSet.Validators())
+	peerManager.AddPeers(peers)
+	go peerManager.RunTransactionGossip()
+	go peerManager.WatchValidators(nodeId, func() []types.Validator {
+		latestHeight, err := blockDB.GetlatestHeight()
+		if err != nil {
+			return nil
+		}
+		return engine.Validators(uint64(latestHeight) + 1)
+	})
 
 	// Init Node
-	node := node.NewNode(peerManager, blockDB, memPool, consensus, isLeader, nodeId)
+	node := node.NewNode(peerManager, blockDB, memPool, engine, nodeId, genesis.BlockProduction)
 	node.Init()
 
 	// Init grpc server
-	server := p2p.NewGRPCServer(blockDB, peerManager, memPool, consensus, isLeader, nodeId)
+	server := p2p.NewGRPCServer(blockDB, peerManager, memPool, engine, nodeId)
 	server.Init(addressPort)
 
 	// === END === //
@@ -3,6 +3,7 @@ package blockchain
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"go-blockchain-ber1/pkg/p2p/pb"
 )
 
 type Block struct {
@@ -11,9 +12,17 @@ type Block struct {
 	PreviousBlockHash []byte
 	CurrentBlockHash  []byte
 	Height            uint64
+	ProposerId        string `json:",omitempty"`
+	ProposerSignature []byte `json:",omitempty"` // Excluded from hash, signs the block hash
+	Round             uint32 `json:",omitempty"`
+	Timestamp         int64  `json:",omitempty"`
+	Nonce             uint64 `json:",omitempty"` // Proof of work
+	Difficulty        uint32 `json:",omitempty"` // Leading zero bits required in the block hash
+
+	Evidence []*pb.Evidence `json:",omitempty"` // Equivocations, their validators are slashed
 }
 
-func NewBlock(transactions []*Transaction, latestBlock *Block) *Block {
+func NewBlock(transactions []*Transaction, latestBlock *Block, proposerId string, round uint32) *Block {
 	var txHashes [][]byte
 	for _, tx := range transactions {
 		txHashes = append(txHashes, tx.Hash())
@@ -25,6 +34,8 @@ func NewBlock(transactions []*Transaction, latestBlock *Block) *Block {
 		MerkleRootHash:    merkleHash,
 		PreviousBlockHash: latestBlock.CurrentBlockHash,
 		Height:            latestBlock.Height + 1,
+		ProposerId:        proposerId,
+		Round:             round,
 	}
 	block.CurrentBlockHash = block.Hash()
 
@@ -34,6 +45,7 @@ func NewBlock(transactions []*Transaction, latestBlock *Block) *Block {
 func (b *Block) Hash() []byte {
 	blockCopy := *b
 	blockCopy.CurrentBlockHash = nil
+	blockCopy.ProposerSignature = nil
 	data, _ := json.Marshal(blockCopy)
 	hash := sha256.Sum256(data)
 	return hash[:]
@@ -1,45 +1,286 @@
 package blockchain
 
 import (
+	"bytes"
+	"errors"
 	"go-blockchain-ber1/pkg/p2p/pb"
 	"log/slog"
+	"sort"
 	"sync"
 )
 
+// MemPoolStore persists pending transactions so they can be replayed after a restart
+type MemPoolStore interface {
+	SaveTransaction(tx *pb.Transaction) error
+	DeleteTransactions(txs []*pb.Transaction) error
+	LoadTransactions() ([]*pb.Transaction, error)
+}
+
+var ErrReplacementUnderpriced = errors.New("replacement transaction must have a strictly higher fee than the pending one")
+
+type MemPoolEventType string
+
+var (
+	MEMPOOL_ADDED   MemPoolEventType = "ADDED"
+	MEMPOOL_REMOVED MemPoolEventType = "REMOVED"
+)
+
+type MemPoolEvent struct {
+	Type        MemPoolEventType
+	Transaction *pb.Transaction
+}
+
+// Events are dropped for a subscriber that does not keep up instead of blocking the mempool
+const memPoolSubscriberBuffer = 256
+
 type MemPool struct {
 	mu                  sync.Mutex
 	pendingTransactions []*pb.Transaction
+	pendingHashes       map[string]bool
+
+	subscribers      map[int]chan MemPoolEvent
+	nextSubscriberId int
+
+	store MemPoolStore
 }
 
-func NewMemPool() *MemPool {
+func NewMemPool(store MemPoolStore) *MemPool {
 	slog.Info("Init mem pool success")
 
 	return &MemPool{
 		pendingTransactions: make([]*pb.Transaction, 0),
+		pendingHashes:       make(map[string]bool),
+		subscribers:         make(map[int]chan MemPoolEvent),
+		store:               store,
+	}
+}
+
+// Restore reloads persisted transactions and keeps only the ones accepted by isValid
+func (m *MemPool) Restore(isValid func(tx *pb.Transaction) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txs, err := m.store.LoadTransactions()
+	if err != nil {
+		return 0, err
+	}
+
+	// Keys are hashes, each sender gets its transactions back in nonce order
+	SortBySenderNonce(txs)
+
+	var invalidTransactions []*pb.Transaction
+	for _, tx := range txs {
+		if !isValid(tx) {
+			invalidTransactions = append(invalidTransactions, tx)
+			continue
+		}
+		m.pendingTransactions = append(m.pendingTransactions, tx)
+		m.pendingHashes[string(pbTransactionHash(tx))] = true
 	}
+
+	if len(invalidTransactions) > 0 {
+		slog.Info("Drop invalid pending transactions", "count", len(invalidTransactions))
+		if err := m.store.DeleteTransactions(invalidTransactions); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(m.pendingTransactions), nil
+}
+
+// SortBySenderNonce orders the transactions by sender, then nonce
+func SortBySenderNonce(txs []*pb.Transaction) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		if c := bytes.Compare(txs[i].Sender, txs[j].Sender); c != 0 {
+			return c < 0
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
 }
 
-func (m *MemPool) AddPendingTransaction(tx *pb.Transaction) {
+// AddPendingTransaction returns false when the transaction is already in the mempool.
+// A transaction with the same sender and nonce as a pending one replaces it only if its fee is strictly higher.
+func (m *MemPool) AddPendingTransaction(tx *pb.Transaction) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	txHash := string(pbTransactionHash(tx))
+	if m.pendingHashes[txHash] {
+		return false, nil
+	}
+
+	for i, pendingTx := range m.pendingTransactions {
+		if !bytes.Equal(pendingTx.Sender, tx.Sender) || pendingTx.Nonce != tx.Nonce {
+			continue
+		}
+
+		if tx.Fee <= pendingTx.Fee {
+			return false, ErrReplacementUnderpriced
+		}
+
+		slog.Info("Replace pending transaction", "sender", string(tx.Sender), "nonce", tx.Nonce, "oldFee", pendingTx.Fee, "newFee", tx.Fee)
+
+		if err := m.store.SaveTransaction(tx); err != nil {
+			return false, err
+		}
+		if err := m.store.DeleteTransactions([]*pb.Transaction{pendingTx}); err != nil {
+			return false, err
+		}
+
+		m.pendingTransactions[i] = tx
+		delete(m.pendingHashes, string(pbTransactionHash(pendingTx)))
+		m.pendingHashes[txHash] = true
+		m.publish(MEMPOOL_REMOVED, pendingTx)
+		m.publish(MEMPOOL_ADDED, tx)
+
+		return true, nil
+	}
+
 	slog.Debug("Add transaction in mempool", "tx", tx)
 
+	if err := m.store.SaveTransaction(tx); err != nil {
+		return false, err
+	}
+
 	m.pendingTransactions = append(m.pendingTransactions, tx)
+	m.pendingHashes[txHash] = true
+	m.publish(MEMPOOL_ADDED, tx)
+
+	return true, nil
+}
+
+// GetPendingNonce returns the highest nonce of the sender in the mempool
+func (m *MemPool) GetPendingNonce(sender []byte) (uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var nonce uint64
+	isFound := false
+	for _, tx := range m.pendingTransactions {
+		if bytes.Equal(tx.Sender, sender) {
+			nonce = max(nonce, tx.Nonce)
+			isFound = true
+		}
+	}
+
+	return nonce, isFound
+}
+
+func (m *MemPool) HasTransaction(txHash []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.pendingHashes[string(txHash)]
 }
 
 func (m *MemPool) GetAllPendingTransactions() []*pb.Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.pendingTransactions
+	return append([]*pb.Transaction{}, m.pendingTransactions...)
+}
+
+// RemoveTransactions removes the transactions included in a committed block,
+// plus the pending ones whose sender nonce is used by the block
+func (m *MemPool) RemoveTransactions(txs []*Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removeHashes := make(map[string]bool)
+	committedNonces := make(map[string]uint64)
+	for _, tx := range txs {
+		removeHashes[string(tx.Hash())] = true
+		committedNonces[string(tx.Sender)] = max(committedNonces[string(tx.Sender)], tx.Nonce)
+	}
+
+	var keptTransactions []*pb.Transaction
+	var removedTransactions []*pb.Transaction
+	for _, tx := range m.pendingTransactions {
+		txHash := string(pbTransactionHash(tx))
+
+		// Pending transactions whose nonce is now used (e.g. a replaced one) can never be included
+		committedNonce, isSenderCommitted := committedNonces[string(tx.Sender)]
+		isNonceUsed := isSenderCommitted && tx.Nonce <= committedNonce
+
+		if removeHashes[txHash] || isNonceUsed {
+			removedTransactions = append(removedTransactions, tx)
+			delete(m.pendingHashes, txHash)
+		} else {
+			keptTransactions = append(keptTransactions, tx)
+		}
+	}
+
+	if len(removedTransactions) == 0 {
+		return
+	}
+
+	slog.Info("Remove committed transactions in mempool", "removed", len(removedTransactions), "remaining", len(keptTransactions))
+
+	if err := m.store.DeleteTransactions(removedTransactions); err != nil {
+		slog.Error("Cant delete persisted pending transactions", "err", err)
+	}
+
+	m.pendingTransactions = keptTransactions
+	for _, tx := range removedTransactions {
+		m.publish(MEMPOOL_REMOVED, tx)
+	}
+}
+
+// ApplyBlocks updates the mempool after the chain changed: transactions of reverted blocks (fork switch)
+// come back when isValid still accepts them, then the ones of committed blocks leave
+func (m *MemPool) ApplyBlocks(committedBlocks []*Block, revertedBlocks []*Block, isValid func(tx *pb.Transaction) bool) {
+	for _, block := range revertedBlocks {
+		for _, tx := range block.Transactions {
+			pbTx := tx.ToPb()
+			if !isValid(pbTx) {
+				continue
+			}
+			if _, err := m.AddPendingTransaction(pbTx); err != nil {
+				slog.Warn("Cant return transaction of reverted block to mempool", "err", err)
+			}
+		}
+	}
+
+	for _, block := range committedBlocks {
+		m.RemoveTransactions(block.Transactions)
+	}
 }
 
-func (m *MemPool) ClearAllPendingTransaction() {
+// Subscribe returns a channel of add/remove events and a function to stop the subscription
+func (m *MemPool) Subscribe() (<-chan MemPoolEvent, func()) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	slog.Info("Remove all pending transactions in mempool")
+	id := m.nextSubscriberId
+	m.nextSubscriberId++
+
+	events := make(chan MemPoolEvent, memPoolSubscriberBuffer)
+	m.subscribers[id] = events
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subscribers[id]; ok {
+			delete(m.subscribers, id)
+			close(events)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// publish must be called with the lock held
+func (m *MemPool) publish(eventType MemPoolEventType, tx *pb.Transaction) {
+	for id, events := range m.subscribers {
+		select {
+		case events <- MemPoolEvent{Type: eventType, Transaction: tx}:
+		default:
+			slog.Warn("Mempool subscriber is too slow, drop event", "subscriber", id)
+		}
+	}
+}
 
-	m.pendingTransactions = []*pb.Transaction{}
+func pbTransactionHash(tx *pb.Transaction) []byte {
+	return TransactionFromPb(tx).Hash()
 }
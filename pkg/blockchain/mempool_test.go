@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"testing"
+)
+
+// memoryMemPoolStore keeps nothing, storage imports this package
+type memoryMemPoolStore struct{}
+
+func (memoryMemPoolStore) SaveTransaction(tx *pb.Transaction) error       { return nil }
+func (memoryMemPoolStore) DeleteTransactions(txs []*pb.Transaction) error { return nil }
+func (memoryMemPoolStore) LoadTransactions() ([]*pb.Transaction, error)   { return nil, nil }
+
+// loadedMemPoolStore returns its transactions as persisted before a restart
+type loadedMemPoolStore struct {
+	memoryMemPoolStore
+	txs []*pb.Transaction
+}
+
+func (s loadedMemPoolStore) LoadTransactions() ([]*pb.Transaction, error) { return s.txs, nil }
+
+func stakingTransactions() (*Transaction, *Transaction) {
+	transfer := &Transaction{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 5, Timestamp: 1700000000, Signature: []byte("sig"), Nonce: 1}
+	stake := *transfer
+	stake.Type, stake.ValidatorId, stake.ValidatorAddress = TX_STAKE, "node4", "localhost:50054"
+	stake.Sender = []byte("carol")
+
+	return transfer, &stake
+}
+
+func TestMemPoolHashesEveryField(t *testing.T) {
+	transfer, stake := stakingTransactions()
+
+	// Same sender and nonce but the type and validator: a different transaction
+	stakeOfAlice := *stake
+	stakeOfAlice.Sender = transfer.Sender
+	if string(pbTransactionHash(stakeOfAlice.ToPb())) == string(pbTransactionHash(transfer.ToPb())) {
+		t.Fatal("stake and transfer have the same mempool hash")
+	}
+
+	memPool := NewMemPool(memoryMemPoolStore{})
+	for _, tx := range []*Transaction{transfer, stake} {
+		if _, err := memPool.AddPendingTransaction(tx.ToPb()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !memPool.HasTransaction(transfer.Hash()) || !memPool.HasTransaction(stake.Hash()) {
+		t.Fatal("mempool hash differs from Transaction.Hash")
+	}
+
+	// Committed stake leaves the mempool by its hash
+	memPool.RemoveTransactions([]*Transaction{stake})
+	if memPool.HasTransaction(stake.Hash()) || len(memPool.GetAllPendingTransactions()) != 1 {
+		t.Fatal("committed stake transaction left in the mempool")
+	}
+}
+
+func TestApplyBlocksReturnsStakingTransactions(t *testing.T) {
+	transfer, stake := stakingTransactions()
+	memPool := NewMemPool(memoryMemPoolStore{})
+
+	reverted := &Block{Transactions: []*Transaction{transfer, stake}}
+	memPool.ApplyBlocks(nil, []*Block{reverted}, func(tx *pb.Transaction) bool { return true })
+
+	pending := memPool.GetAllPendingTransactions()
+	if len(pending) != 2 {
+		t.Fatalf("%d transactions back in the mempool, want 2", len(pending))
+	}
+	if pending[1].Type != TX_STAKE || pending[1].ValidatorId != "node4" || pending[1].ValidatorAddress != "localhost:50054" {
+		t.Fatalf("stake came back as %+v", pending[1])
+	}
+}
+
+func TestRestoreOrdersBySenderAndNonce(t *testing.T) {
+	// Nonce 2 of alice was signed first
+	persisted := []*pb.Transaction{
+		{Sender: []byte("bob"), Receiver: []byte("alice"), Amount: 1, Timestamp: 1700000001, Nonce: 1},
+		{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 1, Timestamp: 1700000002, Nonce: 1},
+		{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 1, Timestamp: 1700000000, Nonce: 2},
+	}
+
+	memPool := NewMemPool(loadedMemPoolStore{txs: persisted})
+	if _, err := memPool.Restore(func(tx *pb.Transaction) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"alice 1", "alice 2", "bob 1"}
+	pending := memPool.GetAllPendingTransactions()
+	if len(pending) != len(want) {
+		t.Fatalf("%d transactions restored, want %d", len(pending), len(want))
+	}
+	for i, tx := range pending {
+		if got := fmt.Sprintf("%s %d", tx.Sender, tx.Nonce); got != want[i] {
+			t.Fatalf("restored transaction %d is %s, want %s", i, got, want[i])
+		}
+	}
+}
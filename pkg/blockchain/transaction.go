@@ -3,23 +3,40 @@ package blockchain
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"go-blockchain-ber1/pkg/p2p/pb"
 	"time"
 )
 
+var (
+	TX_TRANSFER = ""        // Amount moves from sender to receiver
+	TX_STAKE    = "STAKE"   // Amount of the sender is locked as the stake of a validator
+	TX_UNSTAKE  = "UNSTAKE" // Amount of the stake goes back to the balance of the sender
+)
+
 type Transaction struct {
 	Sender    []byte // Public Key or Address
 	Receiver  []byte // Public Key or Address
 	Amount    float64
 	Timestamp int64
-	Signature []byte // R and S concatenated
+	Signature []byte  // R and S concatenated
+	Fee       float64 `json:",omitempty"` // omitempty keeps the hash of transactions created before fees
+	Nonce     uint64  `json:",omitempty"` // Per sender, a pending transaction can be replaced by one with the same nonce
+	PublicKey []byte  `json:",omitempty"` // Excluded from hash, kept so stored transactions can be verified again
+
+	// Staking, the validator public key is the public key of the sender
+	Type             string `json:",omitempty"`
+	ValidatorId      string `json:",omitempty"`
+	ValidatorAddress string `json:",omitempty"` // Required to create a validator
 }
 
-func NewTransaction(sender []byte, receiver []byte, amount float64) *Transaction {
+func NewTransaction(sender []byte, receiver []byte, amount float64, fee float64, nonce uint64) *Transaction {
 	tx := &Transaction{
 		Sender:    sender,
 		Receiver:  receiver,
 		Amount:    amount,
 		Timestamp: time.Now().Unix(),
+		Fee:       fee,
+		Nonce:     nonce,
 	}
 
 	return tx
@@ -29,7 +46,43 @@ func (t *Transaction) Hash() []byte {
 	// Create a hashable representation of the transaction
 	txCopy := *t
 	txCopy.Signature = nil // Exclude signature from hash
+	txCopy.PublicKey = nil
 	data, _ := json.Marshal(txCopy)
 	hash := sha256.Sum256(data)
 	return hash[:]
 }
+
+// TransactionFromPb copies every field of the message, the hash of the copy is the hash of the signed transaction
+func TransactionFromPb(tx *pb.Transaction) *Transaction {
+	return &Transaction{
+		Sender:    tx.Sender,
+		Receiver:  tx.Receiver,
+		Amount:    tx.Amount,
+		Timestamp: tx.Timestamp,
+		Signature: tx.Signature,
+		Fee:       tx.Fee,
+		Nonce:     tx.Nonce,
+		PublicKey: tx.PublicKey,
+
+		Type:             tx.Type,
+		ValidatorId:      tx.ValidatorId,
+		ValidatorAddress: tx.ValidatorAddress,
+	}
+}
+
+func (t *Transaction) ToPb() *pb.Transaction {
+	return &pb.Transaction{
+		Sender:    t.Sender,
+		Receiver:  t.Receiver,
+		Amount:    t.Amount,
+		Timestamp: t.Timestamp,
+		Signature: t.Signature,
+		Fee:       t.Fee,
+		Nonce:     t.Nonce,
+		PublicKey: t.PublicKey,
+
+		Type:             t.Type,
+		ValidatorId:      t.ValidatorId,
+		ValidatorAddress: t.ValidatorAddress,
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"log/slog"
+	"os"
+)
+
+func LoadGenesis(filePath string) (*types.Genesis, error) {
+	if !util.IsFileExist(filePath) {
+		return nil, fmt.Errorf("genesis file not found: %s", filePath)
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var genesis types.Genesis
+	if err := json.Unmarshal(bytes, &genesis); err != nil {
+		return nil, err
+	}
+
+	if len(genesis.Validators) == 0 {
+		return nil, fmt.Errorf("genesis has no validators")
+	}
+
+	validatorIds := make(map[string]bool)
+	for _, validator := range genesis.Validators {
+		if validator.Id == "" || validator.Address == "" {
+			return nil, fmt.Errorf("validator id and address are required")
+		}
+		if validatorIds[validator.Id] {
+			return nil, fmt.Errorf("duplicate validator id: %s", validator.Id)
+		}
+		validatorIds[validator.Id] = true
+
+		if _, err := util.DecodePublicKey(validator.PublicKey); err != nil {
+			return nil, fmt.Errorf("invalid public key of validator %s: %v", validator.Id, err)
+		}
+		if validator.Stake < 0 {
+			return nil, fmt.Errorf("negative stake of validator %s", validator.Id)
+		}
+	}
+
+	if genesis.Pos != nil {
+		if genesis.Pos.EpochLength == 0 {
+			return nil, fmt.Errorf("pos epochLength must be greater than 0")
+		}
+		if genesis.Pos.MinStake < 0 {
+			return nil, fmt.Errorf("pos minStake must not be negative")
+		}
+
+		// Genesis validators form the set of epoch 0, their stake defaults to 1
+		for _, validator := range genesis.Validators {
+			stake := validator.Stake
+			if stake == 0 {
+				stake = 1
+			}
+			if stake < genesis.Pos.MinStake {
+				return nil, fmt.Errorf("stake of validator %s is below pos minStake %v", validator.Id, genesis.Pos.MinStake)
+			}
+		}
+	}
+
+	if production := genesis.BlockProduction; production != nil && (production.BlockInterval < 0 || production.MaxTransactions < 0 || production.MaxBytes < 0) {
+		return nil, fmt.Errorf("blockProduction values must not be negative")
+	}
+
+	slog.Info("Loaded genesis", "chainId", genesis.ChainId, "validators", len(genesis.Validators))
+
+	return &genesis, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGenesis(t *testing.T, genesis *types.Genesis) string {
+	data, _ := json.Marshal(genesis)
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadGenesisMinStake(t *testing.T) {
+	privateKey, _ := wallet.GenerateKeyPair()
+	genesis := &types.Genesis{
+		ChainId: "test",
+		Pos:     &types.PosConfig{EpochLength: 10, MinStake: 10},
+		Validators: []types.Validator{
+			{Id: "node1", Address: "localhost:50051", PublicKey: util.EncodePublicKey(privateKey), Stake: 20},
+			{Id: "node2", Address: "localhost:50052", PublicKey: util.EncodePublicKey(privateKey)},
+		},
+	}
+
+	// node2 has the default stake of 1
+	if _, err := LoadGenesis(writeGenesis(t, genesis)); err == nil || !strings.Contains(err.Error(), "node2") {
+		t.Fatalf("got %v, want node2 below minStake", err)
+	}
+
+	genesis.Validators[1].Stake = 10
+	if _, err := LoadGenesis(writeGenesis(t, genesis)); err != nil {
+		t.Fatal(err)
+	}
+
+	genesis.Pos.MinStake = -1
+	if _, err := LoadGenesis(writeGenesis(t, genesis)); err == nil {
+		t.Fatal("negative minStake accepted")
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"os"
+)
+
+// LoadValidatorKey returns the signing key of the validator: from `encodedKey` when set, else from the keys file
+func LoadValidatorKey(encodedKey string, filePath string, nodeId string) (*ecdsa.PrivateKey, error) {
+	if encodedKey != "" {
+		return util.DecodePrivateKey(encodedKey)
+	}
+
+	if !util.IsFileExist(filePath) {
+		return nil, fmt.Errorf("validator keys file not found: %s", filePath)
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var validatorKeys []types.ValidatorKey
+	if err := json.Unmarshal(bytes, &validatorKeys); err != nil {
+		return nil, err
+	}
+
+	for _, validatorKey := range validatorKeys {
+		if validatorKey.Id == nodeId {
+			return util.DecodePrivateKey(validatorKey.PrivateKey)
+		}
+	}
+
+	return nil, fmt.Errorf("validator key not found: %s", nodeId)
+}
@@ -0,0 +1,69 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/wallet"
+)
+
+func newVoteCertificate(block *pb.Block, votes []*pb.AVote) *pb.QuorumCertificate {
+	return &pb.QuorumCertificate{
+		Height:    block.Height,
+		BlockHash: block.CurrentBlockHash,
+		Votes:     votes,
+	}
+}
+
+func newPbftCertificate(block *pb.Block, commits []*pb.PbftMessage) *pb.QuorumCertificate {
+	return &pb.QuorumCertificate{
+		Height:    block.Height,
+		BlockHash: block.CurrentBlockHash,
+		Commits:   commits,
+	}
+}
+
+// VerifyCertificate checks the certificate holds valid signatures for this block of validators holding a quorum of the voting power.
+// Approving votes and PBFT commits are both accepted, each validator is counted once.
+func (c *Consensus) VerifyCertificate(cert *pb.QuorumCertificate, block *pb.Block) error {
+	if cert == nil {
+		return fmt.Errorf("missing quorum certificate for block %d", block.Height)
+	}
+
+	if cert.Height != block.Height || !bytes.Equal(cert.BlockHash, block.CurrentBlockHash) {
+		return fmt.Errorf("quorum certificate is not for block %d", block.Height)
+	}
+
+	validatorSet := c.ValidatorSetAt(cert.Height)
+
+	var signers []string
+	for _, vote := range cert.Votes {
+		if !vote.Approve || vote.BlockHeight != cert.Height || !bytes.Equal(vote.BlockHash, cert.BlockHash) {
+			continue
+		}
+
+		publicKey := validatorSet.GetPublicKey(vote.NodeId)
+		if publicKey == nil || !verifyVote(c.chainId, vote, publicKey) {
+			continue
+		}
+		signers = append(signers, vote.NodeId)
+	}
+
+	for _, commit := range cert.Commits {
+		if commit.Type != PBFT_COMMIT || commit.Height != cert.Height || !bytes.Equal(commit.BlockHash, cert.BlockHash) {
+			continue
+		}
+
+		publicKey := validatorSet.GetPublicKey(commit.NodeId)
+		if publicKey == nil || !wallet.VerifyHash(pbftSignHash(c.chainId, commit), commit.Signature, publicKey) {
+			continue
+		}
+		signers = append(signers, commit.NodeId)
+	}
+
+	if !validatorSet.HasQuorum(signers) {
+		return fmt.Errorf("quorum certificate of block %d has valid signatures for %v of %v voting power", block.Height, validatorSet.PowerOf(signers), validatorSet.TotalPower())
+	}
+
+	return nil
+}
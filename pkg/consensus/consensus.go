@@ -2,12 +2,12 @@ package consensus
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"fmt"
 	"go-blockchain-ber1/pkg/blockchain"
 	"go-blockchain-ber1/pkg/p2p/pb"
 	"go-blockchain-ber1/pkg/storage"
 	"go-blockchain-ber1/pkg/util"
-	"go-blockchain-ber1/pkg/wallet"
 	"log/slog"
 	"sync"
 )
@@ -15,60 +15,358 @@ import (
 type Consensus struct {
 	mu sync.Mutex
 
-	voters              map[string]bool
-	totalNodeValidators int
+	validatorSet *ValidatorSet // Genesis set, the set of every epoch without proof of stake
+	chainId      string
+	nodeId       string
+	privateKey   *ecdsa.PrivateKey
 
-	proposalBlock *pb.Block
+	views         map[uint64]*view // State machine of each height in flight
+	nextHeight    uint64           // Heights below are committed
+	pipelineDepth uint64           // Heights in flight at once, 1 when not pipelining
+	commitMu      sync.Mutex
+	wal           *storage.WalDB // Proposals and votes of the heights in flight, replayed at restart
+
+	evidence *evidencePool
+
+	epochMu     sync.Mutex
+	epochSets   map[uint64]*ValidatorSet
+	accusedSets map[string]*ValidatorSet // Genesis set without the accused validators (ids), without proof of stake
 
 	blockDB *storage.BlockDB
 }
 
-func NewConsensus(blockDB *storage.BlockDB) *Consensus {
-	slog.Info("Init Consensus success")
+func NewConsensus(blockDB *storage.BlockDB, walDB *storage.WalDB, validatorSet *ValidatorSet, chainId string, nodeId string, privateKey *ecdsa.PrivateKey, pipelineDepth uint64) *Consensus {
+	slog.Info("Init Consensus success", "validators", validatorSet.Size(), "power", validatorSet.TotalPower(), "pipelineDepth", pipelineDepth)
 	return &Consensus{
-		totalNodeValidators: 3,
-		voters:              make(map[string]bool),
-		blockDB:             blockDB,
+		views:         make(map[uint64]*view),
+		pipelineDepth: max(pipelineDepth, 1),
+		wal:           walDB,
+		epochSets:     make(map[uint64]*ValidatorSet),
+		accusedSets:   make(map[string]*ValidatorSet),
+		evidence:      newEvidencePool(),
+		validatorSet:  validatorSet,
+		chainId:       chainId,
+		nodeId:        nodeId,
+		privateKey:    privateKey,
+		blockDB:       blockDB,
+	}
+}
+
+// SignVote signs the vote with the validator key of this node
+func (c *Consensus) SignVote(vote *pb.AVote) error {
+	return signVote(c.chainId, vote, c.privateKey)
+}
+
+// VerifyVote checks the vote is signed by a validator of the set for the current proposal
+func (c *Consensus) VerifyVote(vote *pb.AVote) error {
+	publicKey := c.ValidatorSetAt(vote.BlockHeight).GetPublicKey(vote.NodeId)
+	if publicKey == nil {
+		return fmt.Errorf("vote from unknown validator: %s", vote.NodeId)
+	}
+
+	if !verifyVote(c.chainId, vote, publicKey) {
+		return fmt.Errorf("invalid vote signature from validator: %s", vote.NodeId)
+	}
+
+	proposalBlock := c.GetProposalBlock(vote.BlockHeight)
+	if proposalBlock == nil || proposalBlock.Round != vote.Round || !bytes.Equal(proposalBlock.CurrentBlockHash, vote.BlockHash) {
+		return fmt.Errorf("vote is not for the current proposal block")
 	}
+
+	return nil
 }
 
-func (c *Consensus) HandleVote(vote *pb.AVote) bool {
+// countVote records the vote and returns the commit certificate once validators holding
+// more than 2/3 of the voting power approved the proposal block
+func (c *Consensus) countVote(vote *pb.AVote) (*pb.QuorumCertificate, error) {
+	validatorSet := c.ValidatorSetAt(vote.BlockHeight)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	//Threshold
-	threshold := (2 * (c.totalNodeValidators / 3))
-
 	slog.Debug("Trigger Consensus Handle Vote")
-	slog.Debug("Info Vote : ", "voters", c.voters, "votes", vote, "threshold", threshold)
 
-	// Add vote
-	voteUnique := fmt.Sprintf("%s|%d", vote.NodeId, vote.BlockHeight)
-	c.voters[voteUnique] = vote.Approve
+	// Only validators of the set can vote
+	if !validatorSet.Has(vote.NodeId) {
+		slog.Warn("Ignore vote from unknown validator", "nodeId", vote.NodeId)
+		return nil, nil
+	}
 
-	// Leader alway have approve Vote so that mean init count is 1
-	approveVoteCount := 1
-	for _, vote := range c.voters {
-		if vote {
-			approveVoteCount++
+	// Only the votes for the proposal of the current round count, once
+	view := c.views[vote.BlockHeight]
+	if view == nil || view.step != STEP_VOTE || view.proposal == nil || vote.BlockHeight != view.height || vote.Round != view.round ||
+		!bytes.Equal(view.proposal.CurrentBlockHash, vote.BlockHash) {
+		slog.Debug("Ignore stale vote", "vote", vote)
+		return nil, nil
+	}
+	view.votes[vote.NodeId] = vote
+	if err := c.writeWAL(WAL_VOTE, vote.BlockHeight, vote.Round, nil, vote); err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Info Vote : ", "votes", view.votes, "vote", vote, "totalPower", validatorSet.TotalPower())
+
+	var approveVotes []*pb.AVote
+	var approvers []string
+	for _, vote := range view.votes {
+		if vote.Approve {
+			approveVotes = append(approveVotes, vote)
+			approvers = append(approvers, vote.NodeId)
+		}
+	}
+
+	// Leader alway approve its own block (if leader is a validator)
+	if validatorSet.Has(c.nodeId) {
+		leaderVote := &pb.AVote{
+			Approve:     true,
+			NodeId:      c.nodeId,
+			BlockHeight: vote.BlockHeight,
+			BlockHash:   vote.BlockHash,
+			Round:       view.round,
+		}
+		if err := c.SignVote(leaderVote); err != nil {
+			return nil, err
 		}
+		approveVotes = append(approveVotes, leaderVote)
+		approvers = append(approvers, c.nodeId)
 	}
 
-	if approveVoteCount >= threshold {
-		c.voters = make(map[string]bool)
-		return true
+	// The certificate is committed once the parent is, at once when it is not pipelined
+	if validatorSet.HasQuorum(approvers) {
+		view.enterStep(STEP_COMMIT)
+		view.certificate = newVoteCertificate(view.proposal, approveVotes)
+		return view.certificate, nil
 	}
 
-	return false
+	return nil, nil
 }
 
-func (c *Consensus) HandleProposeBlock(block *pb.Block, latestBlock *blockchain.Block) (bool, error) {
-	// Check Previous Block Hash
+// CreateBlock builds the block on top of the parent with the pending transactions and signs it.
+// The parent is the latest block, or a pending proposal when pipelining.
+func (c *Consensus) CreateBlock(pendingTransactions []*pb.Transaction, parent *blockchain.Block) (*pb.Block, error) {
+	height := parent.Height + 1
+
+	// At most one proposal in flight per height, the proposer sends it again until it is committed or the round fails
+	if c.GetProposalBlock(height) != nil {
+		return nil, ErrProposalInFlight
+	}
+
+	latestBlock, err := c.blockDB.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	pendingBlocks, err := c.pendingBlocks(height, latestBlock)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := newPendingContext(c.blockDB, pendingBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transactions of the pending parents are left out
+	if len(pendingBlocks) > 0 {
+		pendingTransactions = selectTransactions(c.blockDB, ctx, pendingTransactions)
+		if len(pendingTransactions) == 0 {
+			return nil, ErrNoPipelinedTransactions
+		}
+	}
+
+	var bcTransactions []*blockchain.Transaction
+	for _, tx := range pendingTransactions {
+		bcTx := util.ConvertToBlockchainTransaction(tx)
+		bcTransactions = append(bcTransactions, bcTx)
+	}
+
+	round := c.GetRound(height)
+
+	block := blockchain.NewBlock(bcTransactions, parent, c.nodeId, round)
+
+	// Evidence of equivocation seen by this node, committed with the block
+	if evidenceList := c.pendingEvidence(ctx); len(evidenceList) > 0 {
+		block.Evidence = evidenceList
+		block.CurrentBlockHash = block.Hash()
+	}
+
+	pbBlock := util.ConvertToPbBlock(block)
+	pbBlock.Transactions = pendingTransactions
+
+	if !c.signOnce("proposal", pbBlock.Height, pbBlock.Round, pbBlock.CurrentBlockHash) {
+		return nil, ErrRoundProposed
+	}
+	if err := c.SignProposal(pbBlock); err != nil {
+		return nil, err
+	}
+
+	return pbBlock, nil
+}
+
+// Propose keeps the block created by this node, followers send their votes back to it
+func (c *Consensus) Propose(block *pb.Block) (*Output, error) {
+	c.recordProposal(block)
+	if err := c.SetProposalBlock(block); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// HandleProposeBlock validates the block of the proposer and returns the signed vote for it
+func (c *Consensus) HandleProposeBlock(block *pb.Block, latestBlock *blockchain.Block) (*Output, error) {
+	// Check Proposer: proposals from anyone else are rejected without a vote
+	if err := c.verifyProposer(block); err != nil {
+		slog.Warn("Check Fail In: Check Proposer", "err", err)
+		return nil, err
+	}
+
+	// A second block of the proposer at this height and round gets no vote
+	if evidence := c.recordProposal(block); evidence != nil {
+		return &Output{Evidence: []*pb.Evidence{evidence}}, nil
+	}
+
+	isApprove := c.validateProposal(block, latestBlock)
+	if isApprove && !c.signOnce("vote", block.Height, block.Round, block.CurrentBlockHash) {
+		slog.Warn("Reject proposal block, this node already approved another block in this round", "height", block.Height, "round", block.Round)
+		isApprove = false
+	}
+	if isApprove {
+		if err := c.SetProposalBlock(block); err != nil {
+			slog.Warn("Reject proposal block", "err", err)
+			isApprove = false
+		}
+	} else {
+		c.rejectProposal(block)
+	}
+
+	vote := &pb.AVote{
+		Approve:     isApprove,
+		NodeId:      c.nodeId,
+		BlockHeight: block.Height,
+		BlockHash:   block.CurrentBlockHash,
+		Round:       block.Round,
+	}
+	if err := c.SignVote(vote); err != nil {
+		return nil, err
+	}
+	// A restarted node must not approve another block in this round
+	if err := c.writeWAL(WAL_OWN_VOTE, vote.BlockHeight, vote.Round, nil, vote); err != nil {
+		return nil, err
+	}
+
+	// The proposer of the voted height, not always the next one when pipelining
+	message, err := newMessage(c.GetProposer(vote.BlockHeight), MSG_VOTE, vote)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{Messages: []*Message{message}}, nil
+}
+
+// handleVote counts the vote on the proposer, it commits the block and returns its certificate at quorum
+func (c *Consensus) handleVote(vote *pb.AVote) (*Output, error) {
+	if c.GetProposer(vote.BlockHeight) != c.nodeId {
+		slog.Warn("This node is not the proposer of this height for handle vote", "height", vote.BlockHeight)
+		return nil, nil
+	}
+
+	if err := c.checkCurrentRound(vote.BlockHeight, vote.Round); err != nil {
+		slog.Debug("Ignore vote", "err", err)
+		return nil, nil
+	}
+
+	// A validator approving two blocks at this height and round is not counted
+	if evidence := c.recordVote(vote); evidence != nil {
+		return &Output{Evidence: []*pb.Evidence{evidence}}, nil
+	}
+
+	if c.GetProposalBlock(vote.BlockHeight) == nil {
+		return nil, nil
+	}
+
+	if err := c.VerifyVote(vote); err != nil {
+		return nil, err
+	}
+
+	cert, err := c.countVote(vote)
+	if err != nil || cert == nil {
+		return nil, err
+	}
+
+	committedBlocks, needsSync, err := c.commitReady()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{Certificate: cert, CommittedBlocks: committedBlocks, NeedsSync: needsSync}, nil
+}
+
+// HandleCommitBlock commits the proposal block with the certificate of the proposer, a pipelined block once its parent is.
+// When the certified block is not the one this node holds, it must be fetched from peers.
+func (c *Consensus) HandleCommitBlock(cert *pb.QuorumCertificate) (*Output, error) {
+	latestBlock, err := c.blockDB.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil || cert.Height <= latestBlock.Height {
+		slog.Debug("Ignore certificate of a committed height")
+		return nil, nil
+	}
+
+	if err := c.storeCertificate(cert); err != nil {
+		return nil, err
+	}
+
+	committedBlocks, needsSync, err := c.commitReady()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{CommittedBlocks: committedBlocks, NeedsSync: needsSync}, nil
+}
+
+// HandleSyncBlock saves a block fetched from a peer once its quorum certificate is checked
+func (c *Consensus) HandleSyncBlock(block *pb.Block) (*Output, error) {
+	latestBlock, err := c.blockDB.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
 	if !bytes.Equal(latestBlock.CurrentBlockHash, block.PreviousBlockHash) {
+		return nil, fmt.Errorf("block %d does not extend the latest block", block.Height)
+	}
+
+	if err := c.VerifyCertificate(block.Certificate, block); err != nil {
+		return nil, err
+	}
+
+	bcBlock := util.ConvertToBlockchainBlock(block)
+	if err := c.blockDB.SaveBlock(bcBlock, block.Certificate); err != nil {
+		return nil, err
+	}
+	c.evidence.prune(block.Height)
+	c.moveToHeight(block.Height + 1)
+
+	return &Output{CommittedBlocks: []*blockchain.Block{bcBlock}}, nil
+}
+
+// validateProposal checks the block extends the latest block, or its pending parent when pipelining, with valid transactions
+func (c *Consensus) validateProposal(block *pb.Block, latestBlock *blockchain.Block) bool {
+	// Check Pending Parents
+	pendingBlocks, err := c.pendingBlocks(block.Height, latestBlock)
+	if err != nil {
+		slog.Info("Check Fail In: Check Pending Parents", "err", err)
+		return false
+	}
+	parent := latestBlock
+	if len(pendingBlocks) > 0 {
+		parent = util.ConvertToBlockchainBlock(pendingBlocks[len(pendingBlocks)-1])
+	}
+
+	// Check Previous Block Hash
+	if !bytes.Equal(parent.CurrentBlockHash, block.PreviousBlockHash) {
 		slog.Info("Check Fail In: Check Previous Block Hash")
-		slog.Debug("Debug Check Prevous Block Hash : ", "block previous hash", string(block.PreviousBlockHash), "latest block hash", string(latestBlock.CurrentBlockHash))
+		slog.Debug("Debug Check Prevous Block Hash : ", "block previous hash", string(block.PreviousBlockHash), "parent block hash", string(parent.CurrentBlockHash))
 
-		return false, nil
+		return false
 	}
 
 	// Check Merkle Root
@@ -82,66 +380,37 @@ func (c *Consensus) HandleProposeBlock(block *pb.Block, latestBlock *blockchain.
 
 	if !bytes.Equal(merkleRootHash, block.MerkleRootHash) {
 		slog.Info("Check Fail In: Check Merkle Root")
-		return false, nil
+		return false
 	}
 
 	// Check current block hash
 	if !bytes.Equal(bcBlock.Hash(), block.CurrentBlockHash) {
 		slog.Info("Check Fail In: Check current block hash")
-		return false, nil
+		return false
 	}
 
 	// Check block height
-	if block.Height != latestBlock.Height+1 {
+	if block.Height != parent.Height+1 {
 		slog.Info("Check Fail In: Check block height")
-		return false, nil
+		return false
 	}
 
 	//Check Transactions
-	for _, tx := range block.Transactions {
-		bcTx := util.ConvertToBlockchainTransaction(tx)
-		publicKey, _ := util.DecodePublicKey(string(tx.PublicKey))
-
-		if !wallet.VerifyTransaction(bcTx, publicKey) {
-			slog.Info("Check Fail In: Check Transactions")
-			return false, nil
-		}
+	ctx, err := newPendingContext(c.blockDB, pendingBlocks)
+	if err != nil {
+		slog.Info("Check Fail In: Check Pending Parents", "err", err)
+		return false
 	}
-
-	c.SetProposalBlock(block)
-
-	return true, nil
-}
-
-func (c *Consensus) HandleCommitBlock() error {
-	if c.proposalBlock == nil {
-		return nil
+	if err := validateBlockTransactions(c.blockDB, ctx, block.Transactions); err != nil {
+		slog.Info("Check Fail In: Check Transactions", "err", err)
+		return false
 	}
 
-	bcBlock := util.ConvertToBlockchainBlock(c.proposalBlock)
-	if err := c.blockDB.SaveBlock(bcBlock); err != nil {
-		return err
+	// Check Evidence
+	if err := c.validateBlockEvidence(ctx, block.Evidence); err != nil {
+		slog.Info("Check Fail In: Check Evidence", "err", err)
+		return false
 	}
 
-	c.RemoveProposalBlock()
-
-	return nil
-}
-
-// Proposal block
-func (c *Consensus) GetProposalBlock() *pb.Block {
-	return c.proposalBlock
-}
-
-func (c *Consensus) SetProposalBlock(block *pb.Block) {
-	slog.Info("Store Proposal Block")
-	slog.Debug("Set proposal block", "block", block)
-
-	c.proposalBlock = block
-}
-
-func (c *Consensus) RemoveProposalBlock() {
-	slog.Info("Remove proposal block")
-
-	c.proposalBlock = nil
+	return true
 }
@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+)
+
+var (
+	ENGINE_VOTE = "vote"
+	ENGINE_PBFT = "pbft"
+	ENGINE_POW  = "pow"
+)
+
+// Engine is a consensus scheme. Node and grpc server only feed it the blocks and messages
+// received from peers, then apply the returned Output; they don't depend on the scheme.
+type Engine interface {
+	// Validators of the epoch of the height, nil when the engine is permissionless (PoW)
+	Validators(height uint64) []types.Validator
+
+	// Block proposal, the proposer is "" when any node can propose (PoW).
+	// The next block builds on ProposalParent: the latest block, or a pending block when pipelining.
+	GetProposer(height uint64) string
+	ProposalParent(latestBlock *blockchain.Block) *blockchain.Block
+	CreateBlock(pendingTransactions []*pb.Transaction, parent *blockchain.Block) (*pb.Block, error)
+	Propose(block *pb.Block) (*Output, error)
+
+	// Validation and commit
+	HandleProposeBlock(block *pb.Block, latestBlock *blockchain.Block) (*Output, error)
+	HandleCommitBlock(cert *pb.QuorumCertificate) (*Output, error)
+
+	// Messages of the scheme between validators (votes, PBFT phases, heartbeats, view changes), the engine decodes them by type
+	HandleMessage(msg *pb.ConsensusMessage) (*Output, error)
+
+	// Evidence of equivocation gossiped by a peer, kept until a block includes it
+	HandleEvidence(evidence *pb.Evidence) error
+
+	// Blocks fetched from a peer, in height order
+	HandleSyncBlock(block *pb.Block) (*Output, error)
+
+	// Called every HeartbeatInterval: round timeouts, leader failure detection and view change
+	Tick() (*Output, error)
+}
+
+// Output is what the node must send and apply after a step of the engine, nil when there is nothing to do
+type Output struct {
+	Messages        []*Message            // Sent to their validator, broadcast when it has none
+	Certificate     *pb.QuorumCertificate // Broadcast with CommitBlock
+	CommittedBlocks []*blockchain.Block   // Saved by the engine, their transactions leave the mempool
+	RevertedBlocks  []*blockchain.Block   // Rolled back by a fork switch, their transactions go back to the mempool
+	NeedsSync       bool                  // Blocks this node does not hold must be fetched from peers
+	Evidence        []*pb.Evidence        // Equivocation detected by this node, gossiped to every validator
+	Proposal        *pb.Block             // Proposal of this node sent again to every validator
+}
+
+// NewEngine creates the consensus engine by name, the leader vote engine by default
+func NewEngine(name string, genesis *types.Genesis, blockDB *storage.BlockDB, walDB *storage.WalDB, validatorSet *ValidatorSet, nodeId string, privateKey *ecdsa.PrivateKey) (Engine, error) {
+	if name == ENGINE_POW {
+		return NewPoW(blockDB, nodeId, genesis.Pow)
+	}
+
+	var pipelineDepth uint64
+	if genesis.BlockProduction != nil {
+		pipelineDepth = genesis.BlockProduction.PipelineDepth
+	}
+	consensus := NewConsensus(blockDB, walDB, validatorSet, genesis.ChainId, nodeId, privateKey, pipelineDepth)
+
+	var engine Engine
+	var replayEntry func(entry *pb.WalEntry)
+	switch name {
+	case "", ENGINE_VOTE:
+		engine, replayEntry = consensus, consensus.replayEntry
+	case ENGINE_PBFT:
+		pbft := NewPBFT(consensus)
+		engine, replayEntry = pbft, pbft.replayEntry
+	default:
+		return nil, fmt.Errorf("unknown consensus engine: %s", name)
+	}
+
+	// Resume the rounds this node was in before a restart
+	if err := consensus.replayWAL(replayEntry); err != nil {
+		return nil, fmt.Errorf("replay consensus WAL: %w", err)
+	}
+
+	return engine, nil
+}
@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"bytes"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"testing"
+)
+
+func TestEnginesCommitOnEveryValidator(t *testing.T) {
+	engines := []struct {
+		name          string
+		engine        string
+		pipelineDepth uint64
+	}{
+		{"vote", ENGINE_VOTE, 0},
+		{"vote pipelined", ENGINE_VOTE, 3},
+		{"pbft", ENGINE_PBFT, 0},
+	}
+	for _, test := range engines {
+		t.Run(test.name, func(t *testing.T) {
+			genesis, keys := testGenesis(t, 4)
+			genesis.BlockProduction = &types.BlockProductionConfig{PipelineDepth: test.pipelineDepth}
+			network := newTestNetwork(t, genesis, keys, test.engine)
+
+			alice := newTestAccount(t)
+			for nonce := uint64(1); nonce <= 6; nonce += 2 {
+				transactions := []*pb.Transaction{
+					alice.sign(&blockchain.Transaction{Receiver: []byte("bob"), Amount: 1, Nonce: nonce}),
+					alice.sign(&blockchain.Transaction{Receiver: []byte("bob"), Amount: 1, Nonce: nonce + 1}),
+				}
+				if network.produce(transactions) == nil {
+					t.Fatalf("no block proposed for nonce %d", nonce)
+				}
+			}
+
+			latestBlock, _ := network.blockDBs["node1"].GetLatestBlock()
+			for _, id := range network.ids {
+				if height := network.latestHeight(id); height != 4 {
+					t.Fatalf("%s is at height %d, want 4", id, height)
+				}
+				block, _ := network.blockDBs[id].GetLatestBlock()
+				if !bytes.Equal(block.CurrentBlockHash, latestBlock.CurrentBlockHash) {
+					t.Fatalf("%s committed another block 4 than node1", id)
+				}
+				if nonce, _ := network.blockDBs[id].GetAccountNonce(alice.address); nonce != 6 {
+					t.Fatalf("%s has nonce %d for alice, want 6", id, nonce)
+				}
+			}
+		})
+	}
+}
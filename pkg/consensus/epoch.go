@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"go-blockchain-ber1/pkg/types"
+	"log/slog"
+	"strings"
+)
+
+// ValidatorSetAt returns the validator set of the epoch of the height.
+// Without proof of stake it is the genesis set without the accused validators, otherwise the stake table snapshot
+// taken at the end of the previous epoch.
+func (c *Consensus) ValidatorSetAt(height uint64) *ValidatorSet {
+	if !c.blockDB.IsStakingEnabled() {
+		return c.genesisSetAt(height)
+	}
+
+	c.epochMu.Lock()
+	defer c.epochMu.Unlock()
+
+	// An epoch this node has not reached yet uses the latest known set
+	for epoch := c.blockDB.Epoch(height); ; epoch-- {
+		if validatorSet, ok := c.epochSets[epoch]; ok {
+			return validatorSet
+		}
+
+		validators, err := c.blockDB.GetEpochValidators(epoch)
+		if err != nil {
+			slog.Error("Cant get validator set of epoch", "epoch", epoch, "err", err)
+		}
+		if validators != nil {
+			validatorSet := NewValidatorSet(validators)
+			c.epochSets[epoch] = validatorSet
+			if epoch > 0 {
+				slog.Info("Validator set of epoch", "epoch", epoch, "validators", validatorSet.Size(), "power", validatorSet.TotalPower())
+			}
+			return validatorSet
+		}
+
+		if epoch == 0 {
+			return c.validatorSet
+		}
+	}
+}
+
+// Validators returns the validators of the epoch of the height
+func (c *Consensus) Validators(height uint64) []types.Validator {
+	return c.ValidatorSetAt(height).Validators()
+}
+
+// genesisSetAt returns the genesis set without the validators accused by evidence committed below the height.
+// Evidence counts pipelineDepth heights after its block: every height in flight has the same set on every node
+// whether the block is committed or still pending.
+func (c *Consensus) genesisSetAt(height uint64) *ValidatorSet {
+	var validators []types.Validator
+	var accused []string
+	for _, validator := range c.validatorSet.Validators() {
+		accusedHeight, err := c.blockDB.GetAccusedHeight(validator.Id)
+		if err != nil {
+			slog.Error("Cant get accusation of validator", "id", validator.Id, "err", err)
+		}
+		if accusedHeight > 0 && accusedHeight+c.pipelineDepth <= height {
+			accused = append(accused, validator.Id)
+			continue
+		}
+		validators = append(validators, validator)
+	}
+
+	// Without validators no block can be proposed or certified again: the genesis set stays
+	if len(accused) == 0 || len(validators) == 0 {
+		return c.validatorSet
+	}
+
+	c.epochMu.Lock()
+	defer c.epochMu.Unlock()
+
+	id := strings.Join(accused, ",")
+	if validatorSet, ok := c.accusedSets[id]; ok {
+		return validatorSet
+	}
+	validatorSet := NewValidatorSet(validators)
+	c.accusedSets[id] = validatorSet
+	slog.Warn("Accused validators left the set", "accused", accused, "validators", validatorSet.Size(), "power", validatorSet.TotalPower())
+
+	return validatorSet
+}
@@ -0,0 +1,331 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"log/slog"
+	"sync"
+)
+
+var (
+	EVIDENCE_DOUBLE_VOTE     = "DOUBLE_VOTE"
+	EVIDENCE_DOUBLE_PBFT     = "DOUBLE_PBFT"
+	EVIDENCE_DOUBLE_PROPOSAL = "DOUBLE_PROPOSAL"
+)
+
+// signedKey is what a validator signs at most once: one vote, one proposal, one message of each PBFT phase per round
+type signedKey struct {
+	nodeId  string
+	msgType string
+	height  uint64
+	round   uint32
+}
+
+// evidencePool remembers the signed votes, messages and proposals of the heights not committed yet to detect equivocation.
+// It also keeps the evidence waiting to be included in a block.
+type evidencePool struct {
+	mu        sync.Mutex
+	votes     map[signedKey]*pb.AVote
+	messages  map[signedKey]*pb.PbftMessage
+	proposals map[signedKey]*pb.Block
+	pending   map[string]*pb.Evidence // validator id|height -> evidence
+
+	// Block hash of the proposals and approving votes signed by this node
+	ownSigned map[signedKey][]byte
+}
+
+func newEvidencePool() *evidencePool {
+	return &evidencePool{
+		votes:     make(map[signedKey]*pb.AVote),
+		messages:  make(map[signedKey]*pb.PbftMessage),
+		proposals: make(map[signedKey]*pb.Block),
+		pending:   make(map[string]*pb.Evidence),
+		ownSigned: make(map[signedKey][]byte),
+	}
+}
+
+func evidenceId(evidence *pb.Evidence) string {
+	return fmt.Sprintf("%s|%d", evidence.NodeId, evidence.Height)
+}
+
+// addPending keeps the evidence until it is committed, false when the validator is already accused at this height.
+// Must be called with the pool lock held.
+func (pool *evidencePool) addPending(evidence *pb.Evidence) bool {
+	if _, ok := pool.pending[evidenceId(evidence)]; ok {
+		return false
+	}
+
+	pool.pending[evidenceId(evidence)] = evidence
+	slog.Warn("Equivocation detected", "type", evidence.Type, "nodeId", evidence.NodeId, "height", evidence.Height, "round", evidence.Round)
+
+	return true
+}
+
+// prune forgets the signed messages of the committed heights
+func (pool *evidencePool) prune(committedHeight uint64) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for key := range pool.votes {
+		if key.height <= committedHeight {
+			delete(pool.votes, key)
+		}
+	}
+	for key := range pool.messages {
+		if key.height <= committedHeight {
+			delete(pool.messages, key)
+		}
+	}
+	for key := range pool.proposals {
+		if key.height <= committedHeight {
+			delete(pool.proposals, key)
+		}
+	}
+	for key := range pool.ownSigned {
+		if key.height <= committedHeight {
+			delete(pool.ownSigned, key)
+		}
+	}
+}
+
+// signOnce records that this node signs the block as a proposal or an approving vote, false when it already
+// signed another block of this kind at the height and round. A pipelined height dropped because its parent
+// changed round must not be signed again in the same round.
+func (c *Consensus) signOnce(msgType string, height uint64, round uint32, blockHash []byte) bool {
+	c.evidence.mu.Lock()
+	defer c.evidence.mu.Unlock()
+
+	key := signedKey{nodeId: c.nodeId, msgType: msgType, height: height, round: round}
+	if signedHash, ok := c.evidence.ownSigned[key]; ok {
+		return bytes.Equal(signedHash, blockHash)
+	}
+	c.evidence.ownSigned[key] = blockHash
+
+	return true
+}
+
+// recordProposal returns the evidence when the proposer already signed another block at this height and round.
+// The proposer signature must be checked before.
+func (c *Consensus) recordProposal(block *pb.Block) *pb.Evidence {
+	c.evidence.mu.Lock()
+	defer c.evidence.mu.Unlock()
+
+	key := signedKey{nodeId: block.ProposerId, msgType: "proposal", height: block.Height, round: block.Round}
+	seenBlock, ok := c.evidence.proposals[key]
+	if !ok {
+		c.evidence.proposals[key] = block
+		return nil
+	}
+	if bytes.Equal(seenBlock.CurrentBlockHash, block.CurrentBlockHash) {
+		return nil
+	}
+
+	evidence := &pb.Evidence{
+		Type:   EVIDENCE_DOUBLE_PROPOSAL,
+		NodeId: block.ProposerId,
+		Height: block.Height,
+		Round:  block.Round,
+		BlockA: seenBlock,
+		BlockB: block,
+	}
+	if !c.evidence.addPending(evidence) {
+		return nil
+	}
+
+	return evidence
+}
+
+// recordVote returns the evidence when the validator already approved another block at this height and round.
+// Votes with an invalid signature are ignored.
+func (c *Consensus) recordVote(vote *pb.AVote) *pb.Evidence {
+	if !vote.Approve {
+		return nil
+	}
+
+	publicKey := c.ValidatorSetAt(vote.BlockHeight).GetPublicKey(vote.NodeId)
+	if publicKey == nil || !verifyVote(c.chainId, vote, publicKey) {
+		return nil
+	}
+
+	c.evidence.mu.Lock()
+	defer c.evidence.mu.Unlock()
+
+	key := signedKey{nodeId: vote.NodeId, msgType: "vote", height: vote.BlockHeight, round: vote.Round}
+	seenVote, ok := c.evidence.votes[key]
+	if !ok {
+		c.evidence.votes[key] = vote
+		return nil
+	}
+	if bytes.Equal(seenVote.BlockHash, vote.BlockHash) {
+		return nil
+	}
+
+	evidence := &pb.Evidence{
+		Type:   EVIDENCE_DOUBLE_VOTE,
+		NodeId: vote.NodeId,
+		Height: vote.BlockHeight,
+		Round:  vote.Round,
+		VoteA:  seenVote,
+		VoteB:  vote,
+	}
+	if !c.evidence.addPending(evidence) {
+		return nil
+	}
+
+	return evidence
+}
+
+// recordPbftMessage returns the evidence when the validator already sent a message of this phase for another block
+// at this height and view. The signature must be checked before.
+func (c *Consensus) recordPbftMessage(msg *pb.PbftMessage) *pb.Evidence {
+	c.evidence.mu.Lock()
+	defer c.evidence.mu.Unlock()
+
+	key := signedKey{nodeId: msg.NodeId, msgType: msg.Type, height: msg.Height, round: msg.View}
+	seenMsg, ok := c.evidence.messages[key]
+	if !ok {
+		c.evidence.messages[key] = msg
+		return nil
+	}
+	if bytes.Equal(seenMsg.BlockHash, msg.BlockHash) {
+		return nil
+	}
+
+	evidence := &pb.Evidence{
+		Type:     EVIDENCE_DOUBLE_PBFT,
+		NodeId:   msg.NodeId,
+		Height:   msg.Height,
+		Round:    msg.View,
+		MessageA: seenMsg,
+		MessageB: msg,
+	}
+	if !c.evidence.addPending(evidence) {
+		return nil
+	}
+
+	return evidence
+}
+
+// HandleEvidence keeps a valid evidence gossiped by a peer until a block includes it
+func (c *Consensus) HandleEvidence(evidence *pb.Evidence) error {
+	if err := c.VerifyEvidence(evidence); err != nil {
+		return err
+	}
+
+	isCommitted, err := c.blockDB.HasEvidence(evidence.NodeId, evidence.Height)
+	if err != nil || isCommitted {
+		return err
+	}
+
+	c.evidence.mu.Lock()
+	c.evidence.addPending(evidence)
+	c.evidence.mu.Unlock()
+
+	return nil
+}
+
+// pendingEvidence returns the evidence not committed yet nor in the pending blocks of the context, to include in the next block
+func (c *Consensus) pendingEvidence(ctx *pendingContext) []*pb.Evidence {
+	c.evidence.mu.Lock()
+	defer c.evidence.mu.Unlock()
+
+	var evidenceList []*pb.Evidence
+	for id, evidence := range c.evidence.pending {
+		isCommitted, err := c.blockDB.HasEvidence(evidence.NodeId, evidence.Height)
+		if err != nil {
+			continue
+		}
+		if isCommitted {
+			delete(c.evidence.pending, id)
+			continue
+		}
+		if ctx.evidenceIds[id] {
+			continue
+		}
+		evidenceList = append(evidenceList, evidence)
+	}
+
+	return evidenceList
+}
+
+// validateBlockEvidence checks the evidence of a proposal block: valid, not committed yet and once per validator and height
+func (c *Consensus) validateBlockEvidence(ctx *pendingContext, evidenceList []*pb.Evidence) error {
+	for _, evidence := range evidenceList {
+		if ctx.evidenceIds[evidenceId(evidence)] {
+			return fmt.Errorf("duplicate evidence: %s", evidenceId(evidence))
+		}
+		ctx.evidenceIds[evidenceId(evidence)] = true
+
+		if err := c.VerifyEvidence(evidence); err != nil {
+			return err
+		}
+
+		isCommitted, err := c.blockDB.HasEvidence(evidence.NodeId, evidence.Height)
+		if err != nil {
+			return err
+		}
+		if isCommitted {
+			return fmt.Errorf("evidence already committed: %s", evidenceId(evidence))
+		}
+	}
+
+	return nil
+}
+
+// VerifyEvidence checks both messages are signed by the accused validator for two different blocks at the same height and round
+func (c *Consensus) VerifyEvidence(evidence *pb.Evidence) error {
+	publicKey := c.ValidatorSetAt(evidence.Height).GetPublicKey(evidence.NodeId)
+	if publicKey == nil {
+		return fmt.Errorf("evidence against unknown validator: %s", evidence.NodeId)
+	}
+
+	switch evidence.Type {
+	case EVIDENCE_DOUBLE_VOTE:
+		for _, vote := range []*pb.AVote{evidence.VoteA, evidence.VoteB} {
+			if vote == nil || !vote.Approve || vote.NodeId != evidence.NodeId || vote.BlockHeight != evidence.Height || vote.Round != evidence.Round {
+				return fmt.Errorf("double vote evidence needs two approving votes of the validator at the height and round")
+			}
+			if !verifyVote(c.chainId, vote, publicKey) {
+				return fmt.Errorf("invalid vote signature in evidence against %s", evidence.NodeId)
+			}
+		}
+		if bytes.Equal(evidence.VoteA.BlockHash, evidence.VoteB.BlockHash) {
+			return fmt.Errorf("votes of the evidence are for the same block")
+		}
+	case EVIDENCE_DOUBLE_PBFT:
+		for _, msg := range []*pb.PbftMessage{evidence.MessageA, evidence.MessageB} {
+			if msg == nil || msg.NodeId != evidence.NodeId || msg.Height != evidence.Height || msg.View != evidence.Round {
+				return fmt.Errorf("double pbft evidence needs two messages of the validator at the height and view")
+			}
+			if !wallet.VerifyHash(pbftSignHash(c.chainId, msg), msg.Signature, publicKey) {
+				return fmt.Errorf("invalid pbft message signature in evidence against %s", evidence.NodeId)
+			}
+		}
+		if evidence.MessageA.Type != evidence.MessageB.Type || bytes.Equal(evidence.MessageA.BlockHash, evidence.MessageB.BlockHash) {
+			return fmt.Errorf("messages of the evidence are not the same phase for two blocks")
+		}
+	case EVIDENCE_DOUBLE_PROPOSAL:
+		for _, block := range []*pb.Block{evidence.BlockA, evidence.BlockB} {
+			if block == nil || block.ProposerId != evidence.NodeId || block.Height != evidence.Height || block.Round != evidence.Round {
+				return fmt.Errorf("double proposal evidence needs two blocks of the proposer at the height and round")
+			}
+			// The block hash covers the round
+			if !bytes.Equal(util.ConvertToBlockchainBlock(block).Hash(), block.CurrentBlockHash) {
+				return fmt.Errorf("invalid block hash in evidence against %s", evidence.NodeId)
+			}
+			if !wallet.VerifyHash(proposalSignHash(c.chainId, block), block.ProposerSignature, publicKey) {
+				return fmt.Errorf("invalid proposer signature in evidence against %s", evidence.NodeId)
+			}
+		}
+		if bytes.Equal(evidence.BlockA.CurrentBlockHash, evidence.BlockB.CurrentBlockHash) {
+			return fmt.Errorf("blocks of the evidence are the same block")
+		}
+	default:
+		return fmt.Errorf("unknown evidence type: %s", evidence.Type)
+	}
+
+	return nil
+}
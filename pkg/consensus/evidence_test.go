@@ -0,0 +1,120 @@
+package consensus
+
+import (
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// equivocate makes the validator sign a second approving vote for another block before each of its votes
+func equivocate(t *testing.T, network *testNetwork, validatorId string) {
+	network.tamper = func(from string, msg *Message) []*Message {
+		if from != validatorId || msg.Envelope.Type != MSG_VOTE {
+			return []*Message{msg}
+		}
+
+		vote := &pb.AVote{}
+		if err := decodeMessage(msg.Envelope, vote); err != nil || !vote.Approve {
+			return []*Message{msg}
+		}
+		forged := proto.Clone(vote).(*pb.AVote)
+		forged.BlockHash = append([]byte("forged"), vote.BlockHash...)
+		if err := signVote(network.genesis.ChainId, forged, network.keys[validatorId]); err != nil {
+			t.Fatal(err)
+		}
+		forgedMsg, err := newMessage(msg.To, MSG_VOTE, forged)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return []*Message{forgedMsg, msg}
+	}
+}
+
+func TestEquivocatingValidatorIsSlashedAndLeavesSet(t *testing.T) {
+	genesis, keys := testGenesis(t, 4)
+	genesis.Pos = &types.PosConfig{EpochLength: 4}
+	network := newTestNetwork(t, genesis, keys, ENGINE_VOTE)
+	equivocate(t, network, "node2")
+
+	for range 7 {
+		if network.produce(nil) == nil {
+			t.Fatal("no block proposed")
+		}
+	}
+
+	for _, id := range network.ids {
+		if height := network.latestHeight(id); height != 8 {
+			t.Fatalf("%s is at height %d, want 8", id, height)
+		}
+		stake, err := network.blockDBs[id].GetStake("node2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stake.Amount != 0 {
+			t.Fatalf("%s has node2 with stake %v, want slashed to 0", id, stake.Amount)
+		}
+	}
+
+	// node2 is in the set of the epoch of its double vote, not in the next one
+	engine := network.engines["node1"]
+	isValidator := func(height uint64) bool {
+		for _, validator := range engine.Validators(height) {
+			if validator.Id == "node2" {
+				return true
+			}
+		}
+		return false
+	}
+	if !isValidator(4) || isValidator(5) || isValidator(8) {
+		t.Fatalf("node2 in the validator set of height 4: %v, 5: %v, 8: %v, want only 4", isValidator(4), isValidator(5), isValidator(8))
+	}
+}
+
+func TestEquivocatingValidatorLeavesSetWithoutStaking(t *testing.T) {
+	genesis, keys := testGenesis(t, 4)
+	network := newTestNetwork(t, genesis, keys, ENGINE_VOTE)
+	equivocate(t, network, "node2")
+
+	// Block 2 (node3) sees the double vote, block 3 (node4) commits the evidence
+	for range 6 {
+		if network.produce(nil) == nil {
+			t.Fatal("no block proposed")
+		}
+	}
+
+	for _, id := range network.ids {
+		if height := network.latestHeight(id); height != 7 {
+			t.Fatalf("%s is at height %d, want 7", id, height)
+		}
+		if accusedHeight, _ := network.blockDBs[id].GetAccusedHeight("node2"); accusedHeight != 3 {
+			t.Fatalf("%s has node2 accused at height %d, want 3", id, accusedHeight)
+		}
+	}
+
+	engine := network.engines["node1"]
+	for height := uint64(4); height <= 8; height++ {
+		for _, validator := range engine.Validators(height) {
+			if validator.Id == "node2" {
+				t.Fatalf("node2 still in the validator set of height %d", height)
+			}
+		}
+	}
+
+	// node2 neither proposes nor counts in the certificates after its block
+	blockDB := network.blockDBs["node1"]
+	for height := uint64(4); height <= 7; height++ {
+		block, _ := blockDB.GetBlock(height)
+		cert, _ := blockDB.GetCertificate(height)
+		if block.ProposerId == "node2" {
+			t.Fatalf("node2 proposed block %d", height)
+		}
+		for _, vote := range cert.Votes {
+			if vote.NodeId == "node2" {
+				t.Fatalf("vote of node2 counted in the certificate of block %d", height)
+			}
+		}
+	}
+}
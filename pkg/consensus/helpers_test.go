@@ -0,0 +1,73 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"testing"
+)
+
+// testAccount is a key pair with its address, signing transactions
+type testAccount struct {
+	privateKey *ecdsa.PrivateKey
+	address    []byte
+}
+
+func newTestAccount(t testing.TB) *testAccount {
+	privateKey, err := wallet.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testAccount{
+		privateKey: privateKey,
+		address:    wallet.PublicKeyToAddress(&privateKey.PublicKey),
+	}
+}
+
+// sign returns the transaction signed by the account as it is sent to a node
+func (a *testAccount) sign(tx *blockchain.Transaction) *pb.Transaction {
+	tx.Sender = a.address
+	wallet.SignTransaction(tx, a.privateKey)
+
+	pbTx := util.ConvertToPbTransaction(tx)
+	pbTx.PublicKey = []byte(util.EncodePublicKey(a.privateKey))
+	return pbTx
+}
+
+// testGenesis returns a genesis of n validators node1..nodeN of stake 1 and their keys
+func testGenesis(t testing.TB, n int) (*types.Genesis, map[string]*ecdsa.PrivateKey) {
+	genesis := &types.Genesis{ChainId: "test"}
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for i := 1; i <= n; i++ {
+		privateKey, err := wallet.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id := fmt.Sprintf("node%d", i)
+		keys[id] = privateKey
+		genesis.Validators = append(genesis.Validators, types.Validator{
+			Id:        id,
+			Address:   fmt.Sprintf("localhost:%d", 50050+i),
+			PublicKey: util.EncodePublicKey(privateKey),
+		})
+	}
+
+	return genesis, keys
+}
+
+// newTestBlockDB is an initialized block database in memory
+func newTestBlockDB(t testing.TB, genesis *types.Genesis) *storage.BlockDB {
+	blockDB := storage.NewBlockDB(storage.NewMemoryStore())
+	if err := blockDB.Init(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	return blockDB
+}
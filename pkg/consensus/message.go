@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Types of the consensus messages, the payload of the envelope is the protobuf message of the type
+var (
+	MSG_VOTE        = "VOTE"        // pb.AVote, sent to the proposer of the voted height
+	MSG_PBFT        = "PBFT"        // pb.PbftMessage, prepare / commit broadcast to every validator
+	MSG_HEARTBEAT   = "HEARTBEAT"   // pb.Heartbeat, broadcast by the proposer of the next height
+	MSG_VIEW_CHANGE = "VIEW_CHANGE" // pb.ViewChange, broadcast when a round timed out
+)
+
+// Message is a consensus message to send to the validator To, to every peer when To is empty
+type Message struct {
+	To       string
+	Envelope *pb.ConsensusMessage
+}
+
+func newMessage(to string, msgType string, payload proto.Message) (*Message, error) {
+	data, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		To:       to,
+		Envelope: &pb.ConsensusMessage{Type: msgType, Payload: data},
+	}, nil
+}
+
+// decodeMessage unmarshals the payload of the envelope into the message of its type
+func decodeMessage(msg *pb.ConsensusMessage, payload proto.Message) error {
+	if err := proto.Unmarshal(msg.Payload, payload); err != nil {
+		return fmt.Errorf("invalid %s message: %w", msg.Type, err)
+	}
+
+	return nil
+}
+
+// HandleMessage handles the votes, heartbeats and view changes of the leader vote scheme
+func (c *Consensus) HandleMessage(msg *pb.ConsensusMessage) (*Output, error) {
+	latestHeight, err := c.blockDB.GetlatestHeight()
+	if err != nil {
+		return nil, err
+	}
+	nextHeight := uint64(latestHeight) + 1
+
+	switch msg.Type {
+	case MSG_VOTE:
+		vote := &pb.AVote{}
+		if err := decodeMessage(msg, vote); err != nil {
+			return nil, err
+		}
+		return c.handleVote(vote)
+	case MSG_HEARTBEAT:
+		heartbeat := &pb.Heartbeat{}
+		if err := decodeMessage(msg, heartbeat); err != nil {
+			return nil, err
+		}
+		return nil, c.handleHeartbeat(heartbeat, nextHeight)
+	case MSG_VIEW_CHANGE:
+		viewChange := &pb.ViewChange{}
+		if err := decodeMessage(msg, viewChange); err != nil {
+			return nil, err
+		}
+		return c.handleViewChange(viewChange, nextHeight)
+	default:
+		return nil, fmt.Errorf("%s messages are not used by the %s consensus engine", msg.Type, ENGINE_VOTE)
+	}
+}
@@ -0,0 +1,186 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"testing"
+)
+
+// testNetwork runs the engines of the validators in process, each on its own store in memory,
+// and delivers their outputs to each other as PeerManager and the grpc server do
+type testNetwork struct {
+	t        testing.TB
+	genesis  *types.Genesis
+	keys     map[string]*ecdsa.PrivateKey
+	ids      []string
+	engines  map[string]Engine
+	blockDBs map[string]*storage.BlockDB
+
+	// tamper returns the messages a validator really sends instead of msg, to make it misbehave
+	tamper func(from string, msg *Message) []*Message
+}
+
+// delivery is an output of the engine of a validator waiting to be sent
+type delivery struct {
+	from   string
+	output *Output
+}
+
+func newTestNetwork(t testing.TB, genesis *types.Genesis, keys map[string]*ecdsa.PrivateKey, engineName string) *testNetwork {
+	network := &testNetwork{
+		t:        t,
+		genesis:  genesis,
+		keys:     keys,
+		engines:  make(map[string]Engine),
+		blockDBs: make(map[string]*storage.BlockDB),
+	}
+
+	for _, validator := range genesis.Validators {
+		store := storage.NewMemoryStore()
+		blockDB := storage.NewBlockDB(store)
+		if err := blockDB.Init(genesis); err != nil {
+			t.Fatal(err)
+		}
+
+		engine, err := NewEngine(engineName, genesis, blockDB, storage.NewWalDB(store), NewValidatorSet(genesis.Validators), validator.Id, keys[validator.Id])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		network.ids = append(network.ids, validator.Id)
+		network.engines[validator.Id] = engine
+		network.blockDBs[validator.Id] = blockDB
+	}
+
+	return network
+}
+
+// produce lets the proposer of the next height, as node1 sees it, propose a block with the transactions
+// and delivers every message until the network is quiet. It returns the proposed block, nil when none was created.
+func (n *testNetwork) produce(transactions []*pb.Transaction) *pb.Block {
+	latestBlock, err := n.blockDBs[n.ids[0]].GetLatestBlock()
+	if err != nil {
+		n.t.Fatal(err)
+	}
+	height := n.engines[n.ids[0]].ProposalParent(latestBlock).Height + 1
+	proposer := n.engines[n.ids[0]].GetProposer(height)
+
+	engine := n.engines[proposer]
+	latestBlock, err = n.blockDBs[proposer].GetLatestBlock()
+	if err != nil {
+		n.t.Fatal(err)
+	}
+	block, err := engine.CreateBlock(transactions, engine.ProposalParent(latestBlock))
+	if err != nil {
+		return nil
+	}
+	output, err := engine.Propose(block)
+	if err != nil {
+		n.t.Fatal(err)
+	}
+
+	n.deliver(delivery{from: proposer, output: output}, delivery{from: proposer, output: &Output{Proposal: block}})
+
+	return block
+}
+
+// deliver sends the outputs to the other validators, then the outputs of their steps, until there is nothing left
+func (n *testNetwork) deliver(queue ...delivery) {
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if next.output == nil {
+			continue
+		}
+
+		send := func(to string, step func(engine Engine) (*Output, error)) {
+			// A rejected message is dropped as the grpc server does
+			output, _ := step(n.engines[to])
+			if output != nil && output.NeedsSync {
+				n.sync(to)
+			}
+			queue = append(queue, delivery{from: to, output: output})
+		}
+
+		for _, msg := range next.output.Messages {
+			messages := []*Message{msg}
+			if n.tamper != nil {
+				messages = n.tamper(next.from, msg)
+			}
+			for _, msg := range messages {
+				for _, to := range n.receivers(next.from, msg.To) {
+					send(to, func(engine Engine) (*Output, error) { return engine.HandleMessage(msg.Envelope) })
+				}
+			}
+		}
+		for _, to := range n.receivers(next.from, "") {
+			if cert := next.output.Certificate; cert != nil {
+				send(to, func(engine Engine) (*Output, error) { return engine.HandleCommitBlock(cert) })
+			}
+			for _, evidence := range next.output.Evidence {
+				send(to, func(engine Engine) (*Output, error) { return nil, engine.HandleEvidence(evidence) })
+			}
+			if block := next.output.Proposal; block != nil {
+				send(to, func(engine Engine) (*Output, error) {
+					latestBlock, err := n.blockDBs[to].GetLatestBlock()
+					if err != nil {
+						return nil, err
+					}
+					return engine.HandleProposeBlock(block, latestBlock)
+				})
+			}
+		}
+	}
+}
+
+// receivers are the validator the message is for, every other validator when it is for none
+func (n *testNetwork) receivers(from string, to string) []string {
+	if to != "" {
+		return []string{to}
+	}
+
+	var ids []string
+	for _, id := range n.ids {
+		if id != from {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// sync saves the blocks of the highest validator with their certificate
+func (n *testNetwork) sync(id string) {
+	highest := id
+	for _, peer := range n.ids {
+		if n.latestHeight(peer) > n.latestHeight(highest) {
+			highest = peer
+		}
+	}
+
+	for height := n.latestHeight(id) + 1; height <= n.latestHeight(highest); height++ {
+		block, err := n.blockDBs[highest].GetBlock(height)
+		if err != nil {
+			n.t.Fatal(err)
+		}
+		pbBlock := util.ConvertToPbBlock(block)
+		if pbBlock.Certificate, err = n.blockDBs[highest].GetCertificate(height); err != nil {
+			n.t.Fatal(err)
+		}
+		if _, err := n.engines[id].HandleSyncBlock(pbBlock); err != nil {
+			n.t.Fatalf("%s cant sync block %d: %v", id, height, err)
+		}
+	}
+}
+
+func (n *testNetwork) latestHeight(id string) uint64 {
+	latestHeight, err := n.blockDBs[id].GetlatestHeight()
+	if err != nil {
+		n.t.Fatal(err)
+	}
+
+	return uint64(latestHeight)
+}
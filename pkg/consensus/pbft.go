@@ -0,0 +1,379 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"log/slog"
+	"sync"
+)
+
+var (
+	PBFT_PREPARE = "PREPARE"
+	PBFT_COMMIT  = "COMMIT"
+)
+
+// PBFT runs the three phases pre-prepare / prepare / commit among all validators.
+// Every node commits by itself once it has seen a quorum of commit messages, no leader decides for it.
+// Proposer selection, proposal validation and view change (view = round) are shared with Consensus.
+type PBFT struct {
+	*Consensus
+
+	pbftMu sync.Mutex
+	logs   map[pbftLogKey]*pbftLog
+
+	// Transactions of a block prepared at a height: later views only prepare the same transactions
+	lockedBlocks map[uint64]*pb.Block
+}
+
+type pbftLogKey struct {
+	height uint64
+	view   uint32
+}
+
+// Message log of one (view, height)
+type pbftLog struct {
+	block       *pb.Block
+	prepares    map[string][]byte // validator -> block hash
+	commits     map[string]*pb.PbftMessage
+	isPrepared  bool
+	isCommitted bool
+}
+
+func NewPBFT(consensus *Consensus) *PBFT {
+	slog.Info("Init PBFT consensus success")
+
+	// Every node commits by itself with the commits of its own log: a height is only prepared once the previous one is committed
+	consensus.pipelineDepth = 1
+
+	return &PBFT{
+		Consensus:    consensus,
+		logs:         make(map[pbftLogKey]*pbftLog),
+		lockedBlocks: make(map[uint64]*pb.Block),
+	}
+}
+
+// pbftSignHash is the hash signed for prepare / commit: (type, chain id, height, view, block hash)
+func pbftSignHash(chainId string, msg *pb.PbftMessage) []byte {
+	data := []byte(msg.Type)
+	data = binary.AppendUvarint(data, uint64(len(chainId)))
+	data = append(data, chainId...)
+	data = binary.BigEndian.AppendUint64(data, msg.Height)
+	data = binary.BigEndian.AppendUint32(data, msg.View)
+	data = append(data, msg.BlockHash...)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// getLog must be called with the pbft lock held
+func (p *PBFT) getLog(height uint64, view uint32) *pbftLog {
+	key := pbftLogKey{height: height, view: view}
+	if p.logs[key] == nil {
+		p.logs[key] = &pbftLog{
+			prepares: make(map[string][]byte),
+			commits:  make(map[string]*pb.PbftMessage),
+		}
+	}
+
+	return p.logs[key]
+}
+
+func (p *PBFT) createMessage(msgType string, block *pb.Block) (*pb.PbftMessage, error) {
+	msg := &pb.PbftMessage{
+		Type:      msgType,
+		NodeId:    p.nodeId,
+		Height:    block.Height,
+		View:      block.Round,
+		BlockHash: block.CurrentBlockHash,
+	}
+
+	signature, err := wallet.SignHash(pbftSignHash(p.chainId, msg), p.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	msg.Signature = signature
+
+	return msg, nil
+}
+
+// CreateBlock re-proposes the transactions of a block already prepared at this height in an earlier view
+func (p *PBFT) CreateBlock(pendingTransactions []*pb.Transaction, parent *blockchain.Block) (*pb.Block, error) {
+	p.pbftMu.Lock()
+	lockedBlock := p.lockedBlocks[parent.Height+1]
+	p.pbftMu.Unlock()
+
+	if lockedBlock != nil {
+		pendingTransactions = lockedBlock.Transactions
+	}
+
+	return p.Consensus.CreateBlock(pendingTransactions, parent)
+}
+
+// Propose records the pre-prepare of the block created by this node (primary)
+func (p *PBFT) Propose(block *pb.Block) (*Output, error) {
+	p.recordProposal(block)
+	if err := p.SetProposalBlock(block); err != nil {
+		return nil, err
+	}
+
+	return p.acceptPrePrepare(block)
+}
+
+// HandleProposeBlock validates the block proposed by the primary (pre-prepare) and prepares it
+func (p *PBFT) HandleProposeBlock(block *pb.Block, latestBlock *blockchain.Block) (*Output, error) {
+	if err := p.verifyProposer(block); err != nil {
+		slog.Warn("PBFT: Check Fail In: Check Proposer", "err", err)
+		return nil, err
+	}
+
+	// A second pre-prepare of the primary at this height and view is not prepared
+	if evidence := p.recordProposal(block); evidence != nil {
+		return &Output{Evidence: []*pb.Evidence{evidence}}, nil
+	}
+
+	if !p.validateProposal(block, latestBlock) {
+		slog.Info("PBFT: Reject pre-prepare", "height", block.Height, "view", block.Round)
+		p.rejectProposal(block)
+		return nil, nil
+	}
+	if err := p.SetProposalBlock(block); err != nil {
+		return nil, err
+	}
+
+	return p.acceptPrePrepare(block)
+}
+
+// HandleMessage handles the prepare / commit messages, heartbeats and view changes are shared with Consensus.
+// Validators exchange prepare / commit messages instead of votes.
+func (p *PBFT) HandleMessage(msg *pb.ConsensusMessage) (*Output, error) {
+	switch msg.Type {
+	case MSG_PBFT:
+		pbftMessage := &pb.PbftMessage{}
+		if err := decodeMessage(msg, pbftMessage); err != nil {
+			return nil, err
+		}
+		latestHeight, err := p.blockDB.GetlatestHeight()
+		if err != nil {
+			return nil, err
+		}
+		return p.handlePbftMessage(pbftMessage, uint64(latestHeight))
+	case MSG_VOTE:
+		return nil, fmt.Errorf("votes are not used by the %s consensus engine", ENGINE_PBFT)
+	default:
+		return p.Consensus.HandleMessage(msg)
+	}
+}
+
+func (p *PBFT) acceptPrePrepare(block *pb.Block) (*Output, error) {
+	p.pbftMu.Lock()
+	defer p.pbftMu.Unlock()
+
+	if lockedBlock := p.lockedBlocks[block.Height]; lockedBlock != nil && !bytes.Equal(lockedBlock.MerkleRootHash, block.MerkleRootHash) {
+		slog.Warn("PBFT: Reject pre-prepare, another block is prepared at this height", "height", block.Height, "view", block.Round)
+		return nil, nil
+	}
+
+	log := p.getLog(block.Height, block.Round)
+	if log.block != nil {
+		return nil, nil
+	}
+	if !p.signOnce(PBFT_PREPARE, block.Height, block.Round, block.CurrentBlockHash) {
+		slog.Warn("PBFT: Reject pre-prepare, this node already prepared another block in this view", "height", block.Height, "view", block.Round)
+		return nil, nil
+	}
+
+	prepare, err := p.createMessage(PBFT_PREPARE, block)
+	if err != nil {
+		return nil, err
+	}
+	// A restarted node must not prepare another block in this view
+	if err := p.writePbftWAL(prepare); err != nil {
+		return nil, err
+	}
+	log.block = block
+
+	// The pre-prepare counts as the prepare of the primary
+	log.prepares[block.ProposerId] = block.CurrentBlockHash
+	log.prepares[p.nodeId] = block.CurrentBlockHash
+	prepareMessage, err := newMessage("", MSG_PBFT, prepare)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := p.advance(log)
+	if err != nil {
+		return nil, err
+	}
+	output.Messages = append([]*Message{prepareMessage}, output.Messages...)
+
+	return output, nil
+}
+
+// handlePbftMessage records a prepare / commit message.
+// The output holds the block when this message completed its commit.
+func (p *PBFT) handlePbftMessage(msg *pb.PbftMessage, latestHeight uint64) (*Output, error) {
+	validatorSet := p.ValidatorSetAt(msg.Height)
+	publicKey := validatorSet.GetPublicKey(msg.NodeId)
+	if publicKey == nil {
+		return nil, fmt.Errorf("pbft message from unknown validator: %s", msg.NodeId)
+	}
+	if !wallet.VerifyHash(pbftSignHash(p.chainId, msg), msg.Signature, publicKey) {
+		return nil, fmt.Errorf("invalid pbft message signature from validator: %s", msg.NodeId)
+	}
+
+	if msg.Height <= latestHeight {
+		slog.Debug("PBFT: Ignore message of a committed height", "msg", msg)
+		return nil, nil
+	}
+
+	// A validator sending a phase for two blocks at this height and view is not counted
+	if evidence := p.recordPbftMessage(msg); evidence != nil {
+		return &Output{Evidence: []*pb.Evidence{evidence}}, nil
+	}
+
+	if msg.Type != PBFT_PREPARE && msg.Type != PBFT_COMMIT {
+		return nil, fmt.Errorf("unknown pbft message type: %s", msg.Type)
+	}
+
+	p.pbftMu.Lock()
+	defer p.pbftMu.Unlock()
+
+	// Replayed at restart, the log keeps the messages of the peers
+	if err := p.writePbftWAL(msg); err != nil {
+		return nil, err
+	}
+
+	log := p.getLog(msg.Height, msg.View)
+	switch msg.Type {
+	case PBFT_PREPARE:
+		log.prepares[msg.NodeId] = msg.BlockHash
+	case PBFT_COMMIT:
+		log.commits[msg.NodeId] = msg
+	}
+
+	output, err := p.advance(log)
+	if err != nil {
+		return nil, err
+	}
+	output.NeedsSync = log.needsSync(validatorSet)
+
+	return output, nil
+}
+
+// matchingIds returns the validators whose message is for the block
+func matchingIds(votes map[string][]byte, blockHash []byte) []string {
+	var ids []string
+	for nodeId, hash := range votes {
+		if bytes.Equal(hash, blockHash) {
+			ids = append(ids, nodeId)
+		}
+	}
+
+	return ids
+}
+
+func matchingCommits(commits map[string]*pb.PbftMessage, blockHash []byte) ([]*pb.PbftMessage, []string) {
+	var matching []*pb.PbftMessage
+	var ids []string
+	for nodeId, commit := range commits {
+		if bytes.Equal(commit.BlockHash, blockHash) {
+			matching = append(matching, commit)
+			ids = append(ids, nodeId)
+		}
+	}
+
+	return matching, ids
+}
+
+// advance moves the log to prepared / committed when it has a quorum, must be called with the pbft lock held
+func (p *PBFT) advance(log *pbftLog) (*Output, error) {
+	output := &Output{}
+	if log.block == nil || log.isCommitted {
+		return output, nil
+	}
+
+	validatorSet := p.ValidatorSetAt(log.block.Height)
+
+	if !log.isPrepared && validatorSet.HasQuorum(matchingIds(log.prepares, log.block.CurrentBlockHash)) {
+		if !p.signOnce(PBFT_COMMIT, log.block.Height, log.block.Round, log.block.CurrentBlockHash) {
+			slog.Warn("PBFT: Not committing, this node already committed another block in this view", "height", log.block.Height, "view", log.block.Round)
+			return output, nil
+		}
+
+		commit, err := p.createMessage(PBFT_COMMIT, log.block)
+		if err != nil {
+			return nil, err
+		}
+		// A restarted node keeps its lock and does not commit another block in this view
+		if err := p.writeWAL(WAL_PBFT_LOCK, log.block.Height, log.block.Round, log.block, nil); err != nil {
+			return nil, err
+		}
+		if err := p.writePbftWAL(commit); err != nil {
+			return nil, err
+		}
+
+		log.isPrepared = true
+		p.lockedBlocks[log.block.Height] = log.block
+		slog.Info("PBFT: Prepared", "height", log.block.Height, "view", log.block.Round)
+
+		log.commits[p.nodeId] = commit
+		message, err := newMessage("", MSG_PBFT, commit)
+		if err != nil {
+			return nil, err
+		}
+		output.Messages = append(output.Messages, message)
+	}
+
+	commits, committers := matchingCommits(log.commits, log.block.CurrentBlockHash)
+	if log.isPrepared && validatorSet.HasQuorum(committers) {
+		bcBlock := util.ConvertToBlockchainBlock(log.block)
+		if err := p.blockDB.SaveBlock(bcBlock, newPbftCertificate(log.block, commits)); err != nil {
+			return nil, err
+		}
+		log.isCommitted = true
+		slog.Info("PBFT: Committed", "height", log.block.Height, "view", log.block.Round)
+
+		p.moveToHeight(log.block.Height + 1)
+		p.pruneLogs(log.block.Height)
+		p.evidence.prune(log.block.Height)
+
+		output.CommittedBlocks = []*blockchain.Block{bcBlock}
+	}
+
+	return output, nil
+}
+
+// pruneLogs drops the logs and locks of committed heights, must be called with the pbft lock held
+func (p *PBFT) pruneLogs(committedHeight uint64) {
+	for key := range p.logs {
+		if key.height <= committedHeight {
+			delete(p.logs, key)
+		}
+	}
+	for height := range p.lockedBlocks {
+		if height <= committedHeight {
+			delete(p.lockedBlocks, height)
+		}
+	}
+}
+
+// needsSync tells if a quorum committed a block of this log that this node never received
+func (log *pbftLog) needsSync(validatorSet *ValidatorSet) bool {
+	if log.block != nil {
+		return false
+	}
+
+	for _, commit := range log.commits {
+		if _, committers := matchingCommits(log.commits, commit.BlockHash); validatorSet.HasQuorum(committers) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"testing"
+)
+
+// newTestPBFT starts the PBFT engine of the validator on the store, a restart when the store was used before
+func newTestPBFT(t *testing.T, genesis *types.Genesis, keys map[string]*ecdsa.PrivateKey, nodeId string, store storage.Store) *PBFT {
+	blockDB := storage.NewBlockDB(store)
+	if err := blockDB.Init(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewEngine(ENGINE_PBFT, genesis, blockDB, storage.NewWalDB(store), NewValidatorSet(genesis.Validators), nodeId, keys[nodeId])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return engine.(*PBFT)
+}
+
+// signedBlock is the block of the proposer at the round on top of the latest block of the engine
+func signedBlock(t *testing.T, proposer *PBFT, round uint32, transactions []*pb.Transaction) *pb.Block {
+	latestBlock, err := proposer.blockDB.GetLatestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := util.ConvertToPbBlock(blockchain.NewBlock(util.ConvertToBlockchainBlock(&pb.Block{Transactions: transactions}).Transactions, latestBlock, proposer.nodeId, round))
+	block.Transactions = transactions
+	if err := proposer.SignProposal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+// envelope is the PBFT message of the validator as a peer sends it
+func envelope(t *testing.T, sender *PBFT, msgType string, block *pb.Block) *pb.ConsensusMessage {
+	msg, err := sender.createMessage(msgType, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := newMessage("", MSG_PBFT, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return message.Envelope
+}
+
+func handleProposeBlock(t *testing.T, p *PBFT, block *pb.Block) *Output {
+	latestBlock, err := p.blockDB.GetLatestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := p.HandleProposeBlock(block, latestBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return output
+}
+
+// preparedValidator returns node1 of 4 validators once it prepared the block of node3 at height 2, and its store.
+// It sent its prepare and its commit.
+func preparedValidator(t *testing.T) (storage.Store, *types.Genesis, map[string]*ecdsa.PrivateKey, map[string]*PBFT, *pb.Block) {
+	genesis, keys := testGenesis(t, 4)
+	store := storage.NewMemoryStore()
+	peers := map[string]*PBFT{}
+	for _, id := range []string{"node2", "node3", "node4"} {
+		peers[id] = newTestPBFT(t, genesis, keys, id, storage.NewMemoryStore())
+	}
+
+	node1 := newTestPBFT(t, genesis, keys, "node1", store)
+	block := signedBlock(t, peers["node3"], 0, nil)
+	if output := handleProposeBlock(t, node1, block); output == nil || len(output.Messages) != 1 {
+		t.Fatal("pre-prepare not prepared")
+	}
+	output, err := node1.HandleMessage(envelope(t, peers["node2"], PBFT_PREPARE, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Messages) != 1 {
+		t.Fatalf("%d messages after a quorum of prepares, want the commit", len(output.Messages))
+	}
+
+	return store, genesis, keys, peers, block
+}
+
+func TestPBFTRestartedValidatorCommitsFromItsLog(t *testing.T) {
+	store, genesis, keys, peers, block := preparedValidator(t)
+
+	restarted := newTestPBFT(t, genesis, keys, "node1", store)
+	restarted.HandleMessage(envelope(t, peers["node2"], PBFT_COMMIT, block))
+	output, err := restarted.HandleMessage(envelope(t, peers["node3"], PBFT_COMMIT, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Its own commit, replayed, completes the quorum
+	if len(output.CommittedBlocks) != 1 || output.NeedsSync {
+		t.Fatalf("%d blocks committed after the restart (needs sync: %v), want 1", len(output.CommittedBlocks), output.NeedsSync)
+	}
+}
+
+func TestPBFTRestartedValidatorKeepsItsLock(t *testing.T) {
+	store, genesis, keys, peers, _ := preparedValidator(t)
+
+	restarted := newTestPBFT(t, genesis, keys, "node1", store)
+
+	// A restarted validator does not prepare another block of node3 in the same view
+	other := signedBlock(t, peers["node3"], 0, []*pb.Transaction{newTestAccount(t).sign(&blockchain.Transaction{Receiver: []byte("bob"), Amount: 1, Nonce: 1})})
+	if output := handleProposeBlock(t, restarted, other); output != nil && len(output.Messages) > 0 {
+		t.Fatal("restarted validator prepared a second block in the view")
+	}
+
+	// The validators move to view 1, node4 proposes other transactions
+	for _, id := range []string{"node2", "node3", "node4"} {
+		viewChange, err := peers[id].createViewChange(2, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, err := newMessage("", MSG_VIEW_CHANGE, viewChange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := restarted.HandleMessage(message.Envelope); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if round := restarted.GetRound(2); round != 1 {
+		t.Fatalf("restarted validator at round %d, want 1", round)
+	}
+
+	other = signedBlock(t, peers["node4"], 1, other.Transactions)
+	if output := handleProposeBlock(t, restarted, other); output != nil && len(output.Messages) > 0 {
+		t.Fatal("restarted validator prepared other transactions than the block it locked")
+	}
+}
@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"log/slog"
+)
+
+var ErrNoPipelinedTransactions = errors.New("every pending transaction is already in a pending parent")
+
+// ProposalParent returns the block the next proposal builds on: the latest block, or when pipelining
+// the highest pending proposal this node accepted, so height N+1 is proposed while N is being committed
+func (c *Consensus) ProposalParent(latestBlock *blockchain.Block) *blockchain.Block {
+	parent := latestBlock
+	for height := latestBlock.Height + 1; height < latestBlock.Height+c.pipelineDepth; height++ {
+		// The validator set of the next epoch needs the last block of the epoch committed
+		if c.blockDB.IsStakingEnabled() && c.blockDB.Epoch(height) != c.blockDB.Epoch(height+1) {
+			break
+		}
+
+		proposal := c.GetProposalBlock(height)
+		if proposal == nil || !bytes.Equal(proposal.PreviousBlockHash, parent.CurrentBlockHash) {
+			break
+		}
+		parent = util.ConvertToBlockchainBlock(proposal)
+	}
+
+	return parent
+}
+
+// pendingBlocks returns the pending proposals between the latest block and the height, in height order.
+// A proposal at a pipelined height can only be checked against them.
+func (c *Consensus) pendingBlocks(height uint64, latestBlock *blockchain.Block) ([]*pb.Block, error) {
+	if height > latestBlock.Height+1 && c.blockDB.IsStakingEnabled() && c.blockDB.Epoch(height) != c.blockDB.Epoch(height-1) {
+		return nil, fmt.Errorf("block %d starts an epoch, its parent must be committed", height)
+	}
+
+	var pendingBlocks []*pb.Block
+	parentHash := latestBlock.CurrentBlockHash
+	for pendingHeight := latestBlock.Height + 1; pendingHeight < height; pendingHeight++ {
+		proposal := c.GetProposalBlock(pendingHeight)
+		if proposal == nil || !bytes.Equal(proposal.PreviousBlockHash, parentHash) {
+			return nil, fmt.Errorf("pending parent %d of block %d is unknown", pendingHeight, height)
+		}
+		pendingBlocks = append(pendingBlocks, proposal)
+		parentHash = proposal.CurrentBlockHash
+	}
+
+	return pendingBlocks, nil
+}
+
+// storeCertificate keeps the certificate of a height until its parent is committed.
+// A certificate for a block this node does not hold is kept too, committing it needs a sync.
+func (c *Consensus) storeCertificate(cert *pb.QuorumCertificate) error {
+	proposal := c.GetProposalBlock(cert.Height)
+	if proposal != nil && bytes.Equal(proposal.CurrentBlockHash, cert.BlockHash) {
+		if err := c.VerifyCertificate(cert, proposal); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if view := c.getView(cert.Height); view != nil {
+		view.certificate = cert
+	}
+
+	return nil
+}
+
+// commitReady commits in height order the pending proposals that have their certificate.
+// It tells when the next height is certified for a block this node does not hold.
+func (c *Consensus) commitReady() ([]*blockchain.Block, bool, error) {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	var committedBlocks []*blockchain.Block
+	for {
+		latestBlock, err := c.blockDB.GetLatestBlock()
+		if err != nil {
+			return committedBlocks, false, err
+		}
+
+		c.mu.Lock()
+		var proposal *pb.Block
+		var cert *pb.QuorumCertificate
+		if view, ok := c.views[latestBlock.Height+1]; ok {
+			proposal, cert = view.proposal, view.certificate
+		}
+		c.mu.Unlock()
+
+		if cert == nil {
+			return committedBlocks, false, nil
+		}
+		if proposal == nil || !bytes.Equal(proposal.CurrentBlockHash, cert.BlockHash) || !bytes.Equal(proposal.PreviousBlockHash, latestBlock.CurrentBlockHash) {
+			return committedBlocks, true, nil
+		}
+
+		if err := c.VerifyCertificate(cert, proposal); err != nil {
+			return committedBlocks, false, err
+		}
+
+		bcBlock := util.ConvertToBlockchainBlock(proposal)
+		if err := c.blockDB.SaveBlock(bcBlock, cert); err != nil {
+			return committedBlocks, false, err
+		}
+		if len(committedBlocks) > 0 {
+			slog.Info("Commit pipelined block", "height", bcBlock.Height)
+		}
+
+		c.moveToHeight(bcBlock.Height + 1)
+		c.evidence.prune(bcBlock.Height)
+
+		committedBlocks = append(committedBlocks, bcBlock)
+	}
+}
@@ -0,0 +1,431 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"log/slog"
+	"math/big"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Defaults of the PoW engine, low enough to mine in a demo
+var defaultPowConfig = types.PowConfig{
+	InitialDifficulty: 12,
+	MinDifficulty:     1,
+	TargetBlockTime:   10,
+	RetargetInterval:  10,
+}
+
+const (
+	// Blocks from further in the future are rejected
+	maxFutureBlockTime = 2 * time.Minute
+
+	// The miner checks every this many hashes that no other block was found at its height
+	miningCheckInterval = 1024
+)
+
+var ErrStaleBlock = errors.New("a block was found at this height while mining")
+
+// PoW is a permissionless proof of work engine: every node mines the next block,
+// and the chain with the most work (sum of 2^difficulty) is the main chain.
+type PoW struct {
+	mu sync.Mutex
+
+	nodeId  string
+	config  types.PowConfig
+	blockDB *storage.BlockDB
+
+	// Every known block by hash: main chain and forks
+	blocks map[string]*powBlock
+	tip    *powBlock
+}
+
+type powBlock struct {
+	block       *pb.Block
+	parent      *powBlock
+	totalWork   *big.Int
+	isMainChain bool
+}
+
+// NewPoW loads the main chain from the database
+func NewPoW(blockDB *storage.BlockDB, nodeId string, config *types.PowConfig) (*PoW, error) {
+	p := &PoW{
+		nodeId:  nodeId,
+		config:  defaultPowConfig,
+		blockDB: blockDB,
+		blocks:  make(map[string]*powBlock),
+	}
+	if config != nil {
+		if config.InitialDifficulty > 0 {
+			p.config.InitialDifficulty = config.InitialDifficulty
+		}
+		if config.MinDifficulty > 0 {
+			p.config.MinDifficulty = config.MinDifficulty
+		}
+		if config.TargetBlockTime > 0 {
+			p.config.TargetBlockTime = config.TargetBlockTime
+		}
+		if config.RetargetInterval > 0 {
+			p.config.RetargetInterval = config.RetargetInterval
+		}
+	}
+
+	latestHeight, err := blockDB.GetlatestHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *powBlock
+	for height := uint64(1); height <= uint64(latestHeight); height++ {
+		block, err := blockDB.GetBlock(height)
+		if err != nil {
+			return nil, err
+		}
+
+		node := &powBlock{
+			block:       util.ConvertToPbBlock(block),
+			parent:      parent,
+			totalWork:   blockWork(block.Difficulty),
+			isMainChain: true,
+		}
+		if parent != nil {
+			node.totalWork.Add(node.totalWork, parent.totalWork)
+		}
+		p.blocks[hex.EncodeToString(block.CurrentBlockHash)] = node
+		parent = node
+	}
+	p.tip = parent
+
+	slog.Info("Init PoW consensus success", "height", latestHeight, "difficulty", p.config.InitialDifficulty)
+
+	return p, nil
+}
+
+// blockWork is the expected number of hashes to find a block of this difficulty
+func blockWork(difficulty uint32) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(difficulty))
+}
+
+func hasProofOfWork(hash []byte, difficulty uint32) bool {
+	zeroBits := 0
+	for _, b := range hash {
+		if b != 0 {
+			zeroBits += bits.LeadingZeros8(b)
+			break
+		}
+		zeroBits += 8
+	}
+
+	return zeroBits >= int(difficulty)
+}
+
+// expectedDifficulty of the child of the block. Every RetargetInterval blocks the difficulty goes
+// up by one bit when blocks came twice faster than TargetBlockTime, down by one when twice slower.
+func (p *PoW) expectedDifficulty(parent *powBlock) uint32 {
+	// First mined block
+	if parent.block.Difficulty == 0 {
+		return p.config.InitialDifficulty
+	}
+
+	if parent.block.Height%p.config.RetargetInterval != 0 {
+		return parent.block.Difficulty
+	}
+
+	ancestor := parent
+	for i := uint64(0); i < p.config.RetargetInterval && ancestor.parent != nil; i++ {
+		ancestor = ancestor.parent
+	}
+	// The window reaches the genesis block, which has no timestamp
+	if ancestor.block.Timestamp == 0 {
+		return parent.block.Difficulty
+	}
+
+	actualTime := parent.block.Timestamp - ancestor.block.Timestamp
+	expectedTime := int64(p.config.RetargetInterval) * p.config.TargetBlockTime
+
+	difficulty := parent.block.Difficulty
+	switch {
+	case actualTime < expectedTime/2:
+		difficulty++
+	case actualTime > expectedTime*2 && difficulty > p.config.MinDifficulty:
+		difficulty--
+	}
+
+	if difficulty != parent.block.Difficulty {
+		slog.Info("PoW: Retarget difficulty", "height", parent.block.Height+1, "difficulty", difficulty, "actualTime", actualTime, "expectedTime", expectedTime)
+	}
+
+	return difficulty
+}
+
+// Validators is nil: proof of work has no validator set
+func (p *PoW) Validators(height uint64) []types.Validator {
+	return nil
+}
+
+// GetProposer is empty: any node can mine the next block
+func (p *PoW) GetProposer(height uint64) string {
+	return ""
+}
+
+// ProposalParent is the tip of the main chain: a mined block is final for its miner, there is nothing to pipeline
+func (p *PoW) ProposalParent(latestBlock *blockchain.Block) *blockchain.Block {
+	return latestBlock
+}
+
+// CreateBlock mines the next block on the main chain.
+// It gives up with ErrStaleBlock as soon as another block extends the chain.
+func (p *PoW) CreateBlock(pendingTransactions []*pb.Transaction, latestBlock *blockchain.Block) (*pb.Block, error) {
+	p.mu.Lock()
+	parent := p.tip
+	p.mu.Unlock()
+
+	if !bytes.Equal(parent.block.CurrentBlockHash, latestBlock.CurrentBlockHash) {
+		return nil, ErrStaleBlock
+	}
+
+	var bcTransactions []*blockchain.Transaction
+	for _, tx := range pendingTransactions {
+		bcTransactions = append(bcTransactions, util.ConvertToBlockchainTransaction(tx))
+	}
+
+	block := blockchain.NewBlock(bcTransactions, latestBlock, p.nodeId, 0)
+	block.Timestamp = time.Now().Unix()
+	block.Difficulty = p.expectedDifficulty(parent)
+
+	startTime := time.Now()
+	for nonce := uint64(0); ; nonce++ {
+		block.Nonce = nonce
+		hash := block.Hash()
+		if hasProofOfWork(hash, block.Difficulty) {
+			block.CurrentBlockHash = hash
+			break
+		}
+
+		if nonce%miningCheckInterval == 0 && p.getTip() != parent {
+			return nil, ErrStaleBlock
+		}
+	}
+	slog.Info("PoW: Mined block", "height", block.Height, "difficulty", block.Difficulty, "nonce", block.Nonce, "duration", time.Since(startTime))
+
+	pbBlock := util.ConvertToPbBlock(block)
+	pbBlock.Transactions = pendingTransactions
+
+	return pbBlock, nil
+}
+
+func (p *PoW) getTip() *powBlock {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.tip
+}
+
+// Propose adds the block mined by this node to the main chain
+func (p *PoW) Propose(block *pb.Block) (*Output, error) {
+	return p.handleBlock(block)
+}
+
+// HandleProposeBlock adds a block mined by a peer, the chain switches to its fork if it has more work
+func (p *PoW) HandleProposeBlock(block *pb.Block, latestBlock *blockchain.Block) (*Output, error) {
+	return p.handleBlock(block)
+}
+
+// HandleSyncBlock adds a block fetched from a peer like a mined one
+func (p *PoW) HandleSyncBlock(block *pb.Block) (*Output, error) {
+	return p.handleBlock(block)
+}
+
+func (p *PoW) handleBlock(block *pb.Block) (*Output, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	blockKey := hex.EncodeToString(block.CurrentBlockHash)
+	if p.blocks[blockKey] != nil {
+		return nil, nil
+	}
+
+	parent := p.blocks[hex.EncodeToString(block.PreviousBlockHash)]
+	if parent == nil {
+		slog.Info("PoW: Parent of block unknown, sync with peers", "height", block.Height)
+		return &Output{NeedsSync: true}, nil
+	}
+
+	if err := p.verifyBlock(block, parent); err != nil {
+		return nil, err
+	}
+
+	node := &powBlock{
+		block:     block,
+		parent:    parent,
+		totalWork: new(big.Int).Add(parent.totalWork, blockWork(block.Difficulty)),
+	}
+	p.blocks[blockKey] = node
+
+	if node.totalWork.Cmp(p.tip.totalWork) <= 0 {
+		slog.Info("PoW: Block added to a fork with less work", "height", block.Height, "proposer", block.ProposerId)
+		return nil, nil
+	}
+
+	return p.switchTo(node)
+}
+
+// verifyBlock checks the header of the block against its parent, transactions are checked when it joins the main chain
+func (p *PoW) verifyBlock(block *pb.Block, parent *powBlock) error {
+	if block.Height != parent.block.Height+1 {
+		return fmt.Errorf("block height %d does not follow its parent %d", block.Height, parent.block.Height)
+	}
+	if len(block.Evidence) > 0 {
+		return fmt.Errorf("block %d carries evidence, there are no validators to slash", block.Height)
+	}
+
+	bcBlock := util.ConvertToBlockchainBlock(block)
+
+	var txHashes [][]byte
+	for _, tx := range bcBlock.Transactions {
+		txHashes = append(txHashes, tx.Hash())
+	}
+	if !bytes.Equal(blockchain.BuildMerkleRoot(txHashes), block.MerkleRootHash) {
+		return fmt.Errorf("merkle root of block %d does not match", block.Height)
+	}
+
+	if !bytes.Equal(bcBlock.Hash(), block.CurrentBlockHash) {
+		return fmt.Errorf("hash of block %d does not match", block.Height)
+	}
+
+	if expectedDifficulty := p.expectedDifficulty(parent); block.Difficulty != expectedDifficulty {
+		return fmt.Errorf("block %d has difficulty %d, expected %d", block.Height, block.Difficulty, expectedDifficulty)
+	}
+
+	if !hasProofOfWork(block.CurrentBlockHash, block.Difficulty) {
+		return fmt.Errorf("block %d does not have the proof of work", block.Height)
+	}
+
+	if block.Timestamp < parent.block.Timestamp || time.Unix(block.Timestamp, 0).After(time.Now().Add(maxFutureBlockTime)) {
+		return fmt.Errorf("block %d has an invalid timestamp", block.Height)
+	}
+
+	return nil
+}
+
+// switchTo makes the block the tip of the main chain: blocks of the old chain above the common
+// ancestor are reverted, then the blocks of the new chain are saved, must be called with the lock held.
+// A block with invalid transactions is dropped with its descendants and the chain stops before it,
+// the old chain comes back when the valid part of the fork does not have more work.
+func (p *PoW) switchTo(newTip *powBlock) (*Output, error) {
+	var newBlocks []*powBlock
+	ancestor := newTip
+	for !ancestor.isMainChain {
+		newBlocks = append(newBlocks, ancestor)
+		ancestor = ancestor.parent
+	}
+
+	oldTip := p.tip
+	output := &Output{}
+	if ancestor != p.tip {
+		slog.Warn("PoW: Switch to fork with more work", "commonHeight", ancestor.block.Height, "oldHeight", p.tip.block.Height, "newHeight", newTip.block.Height)
+
+		revertedBlocks, err := p.blockDB.RewindTo(ancestor.block.Height)
+		if err != nil {
+			return nil, err
+		}
+		for node := p.tip; node != ancestor; node = node.parent {
+			node.isMainChain = false
+		}
+		p.tip = ancestor
+		output.RevertedBlocks = revertedBlocks
+	}
+
+	for i := len(newBlocks) - 1; i >= 0; i-- {
+		node := newBlocks[i]
+
+		ctx, err := newPendingContext(p.blockDB, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateBlockTransactions(p.blockDB, ctx, node.block.Transactions); err != nil {
+			slog.Warn("PoW: Drop block with invalid transactions", "height", node.block.Height, "err", err)
+			for _, invalidNode := range newBlocks[:i+1] {
+				delete(p.blocks, hex.EncodeToString(invalidNode.block.CurrentBlockHash))
+			}
+			break
+		}
+
+		bcBlock := util.ConvertToBlockchainBlock(node.block)
+		if err := p.blockDB.SaveBlock(bcBlock, nil); err != nil {
+			return nil, err
+		}
+		node.isMainChain = true
+		p.tip = node
+
+		output.CommittedBlocks = append(output.CommittedBlocks, bcBlock)
+	}
+
+	if p.tip.totalWork.Cmp(oldTip.totalWork) <= 0 && p.tip != oldTip {
+		slog.Warn("PoW: Fork has invalid blocks, switch back to the old chain", "forkHeight", p.tip.block.Height, "height", oldTip.block.Height)
+		if err := p.restoreChain(ancestor, oldTip); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	slog.Info("PoW: New tip", "height", p.tip.block.Height, "difficulty", p.tip.block.Difficulty, "totalWork", p.tip.totalWork)
+
+	return output, nil
+}
+
+// restoreChain rewinds the saved fork blocks and saves again the blocks of the old chain above the ancestor,
+// they were valid on it. Must be called with the lock held.
+func (p *PoW) restoreChain(ancestor *powBlock, oldTip *powBlock) error {
+	if _, err := p.blockDB.RewindTo(ancestor.block.Height); err != nil {
+		return err
+	}
+	for node := p.tip; node != ancestor; node = node.parent {
+		node.isMainChain = false
+	}
+	p.tip = ancestor
+
+	var oldBlocks []*powBlock
+	for node := oldTip; node != ancestor; node = node.parent {
+		oldBlocks = append(oldBlocks, node)
+	}
+	for i := len(oldBlocks) - 1; i >= 0; i-- {
+		node := oldBlocks[i]
+		if err := p.blockDB.SaveBlock(util.ConvertToBlockchainBlock(node.block), nil); err != nil {
+			return err
+		}
+		node.isMainChain = true
+		p.tip = node
+	}
+
+	return nil
+}
+
+// HandleMessage rejects consensus messages: miners only exchange blocks
+func (p *PoW) HandleMessage(msg *pb.ConsensusMessage) (*Output, error) {
+	return nil, fmt.Errorf("%s messages are not used by the %s consensus engine", msg.Type, ENGINE_POW)
+}
+
+// HandleCommitBlock is not part of PoW, a mined block is added as soon as it is received
+func (p *PoW) HandleCommitBlock(cert *pb.QuorumCertificate) (*Output, error) {
+	return nil, nil
+}
+
+// HandleEvidence rejects evidence: without validators there is nothing to slash
+func (p *PoW) HandleEvidence(evidence *pb.Evidence) error {
+	return fmt.Errorf("evidence is not used by the %s consensus engine", ENGINE_POW)
+}
+
+// Tick does nothing, PoW has no leader: no heartbeat and no view change
+func (p *PoW) Tick() (*Output, error) {
+	return nil, nil
+}
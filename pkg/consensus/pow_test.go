@@ -0,0 +1,105 @@
+package consensus
+
+import (
+	"bytes"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"testing"
+)
+
+// mineBlock mines a block of difficulty 1 on top of the parent, miners of a fork differ
+func mineBlock(miner string, parent *pb.Block, transactions []*pb.Transaction) *pb.Block {
+	block := blockchain.NewBlock(util.ConvertToBlockchainBlock(&pb.Block{Transactions: transactions}).Transactions, util.ConvertToBlockchainBlock(parent), miner, 0)
+	block.Timestamp = parent.Timestamp + 1
+	block.Difficulty = 1
+	for block.CurrentBlockHash = block.Hash(); !hasProofOfWork(block.CurrentBlockHash, block.Difficulty); block.CurrentBlockHash = block.Hash() {
+		block.Nonce++
+	}
+
+	pbBlock := util.ConvertToPbBlock(block)
+	pbBlock.Transactions = transactions
+	return pbBlock
+}
+
+func newTestPoW(t *testing.T) (*PoW, *pb.Block) {
+	genesis, _ := testGenesis(t, 1)
+	blockDB := newTestBlockDB(t, genesis)
+
+	pow, err := NewPoW(blockDB, "node1", &types.PowConfig{InitialDifficulty: 1, MinDifficulty: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pow, pow.getTip().block
+}
+
+func TestPoWSwitchesToForkWithMoreWork(t *testing.T) {
+	pow, genesisBlock := newTestPoW(t)
+
+	main := mineBlock("node1", genesisBlock, nil)
+	if _, err := pow.HandleProposeBlock(main, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fork1 := mineBlock("node2", genesisBlock, nil)
+	fork2 := mineBlock("node2", fork1, nil)
+	pow.HandleProposeBlock(fork1, nil)
+	output, err := pow.HandleProposeBlock(fork2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.RevertedBlocks) != 1 || len(output.CommittedBlocks) != 2 {
+		t.Fatalf("%d reverted and %d committed blocks, want 1 and 2", len(output.RevertedBlocks), len(output.CommittedBlocks))
+	}
+	latest, _ := pow.blockDB.GetLatestBlock()
+	if !bytes.Equal(latest.CurrentBlockHash, fork2.CurrentBlockHash) {
+		t.Fatal("tip is not the fork")
+	}
+}
+
+func TestPoWKeepsChainWhenForkIsInvalid(t *testing.T) {
+	pow, genesisBlock := newTestPoW(t)
+
+	main1 := mineBlock("node1", genesisBlock, nil)
+	main2 := mineBlock("node1", main1, nil)
+	for _, block := range []*pb.Block{main1, main2} {
+		if _, err := pow.HandleProposeBlock(block, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Heavier fork whose first block holds a transaction with a forged signature
+	forged := newTestAccount(t).sign(&blockchain.Transaction{Receiver: []byte("bob"), Amount: 1, Nonce: 1})
+	forged.Amount = 1000
+	fork1 := mineBlock("node2", genesisBlock, []*pb.Transaction{forged})
+	fork2 := mineBlock("node2", fork1, nil)
+	fork3 := mineBlock("node2", fork2, nil)
+	for _, block := range []*pb.Block{fork1, fork2, fork3} {
+		output, err := pow.HandleProposeBlock(block, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != nil && (len(output.RevertedBlocks) > 0 || len(output.CommittedBlocks) > 0) {
+			t.Fatalf("invalid fork changed the chain: %d reverted, %d committed", len(output.RevertedBlocks), len(output.CommittedBlocks))
+		}
+	}
+
+	latestHeight, _ := pow.blockDB.GetlatestHeight()
+	latest, _ := pow.blockDB.GetLatestBlock()
+	if latestHeight != 3 || !bytes.Equal(latest.CurrentBlockHash, main2.CurrentBlockHash) {
+		t.Fatalf("chain is at height %d, want the main chain at height 3", latestHeight)
+	}
+	if pow.getTip().block != main2 {
+		t.Fatal("tip of the engine is not the main chain")
+	}
+
+	// The main chain keeps growing
+	main3 := mineBlock("node1", main2, nil)
+	output, err := pow.HandleProposeBlock(main3, nil)
+	if err != nil || output == nil || len(output.CommittedBlocks) != 1 {
+		t.Fatalf("block on the restored chain not committed: %v", err)
+	}
+}
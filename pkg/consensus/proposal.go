@@ -0,0 +1,60 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/wallet"
+)
+
+// proposalSignHash is the hash signed by the proposer: (chain id, height, block hash)
+func proposalSignHash(chainId string, block *pb.Block) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(chainId)))
+	data = append(data, chainId...)
+	data = binary.BigEndian.AppendUint64(data, block.Height)
+	data = append(data, block.CurrentBlockHash...)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func signProposal(chainId string, block *pb.Block, privKey *ecdsa.PrivateKey) error {
+	signature, err := wallet.SignHash(proposalSignHash(chainId, block), privKey)
+	if err != nil {
+		return err
+	}
+
+	block.ProposerSignature = signature
+	return nil
+}
+
+// SignProposal signs the proposal block with the validator key of this node
+func (c *Consensus) SignProposal(block *pb.Block) error {
+	return signProposal(c.chainId, block, c.privateKey)
+}
+
+// GetProposer returns the validator expected to propose the block at this height in the current round
+func (c *Consensus) GetProposer(height uint64) string {
+	return c.ValidatorSetAt(height).Proposer(height, c.GetRound(height)).Id
+}
+
+// verifyProposer checks the block is proposed in the current round and signed by the expected proposer
+func (c *Consensus) verifyProposer(block *pb.Block) error {
+	if err := c.checkCurrentRound(block.Height, block.Round); err != nil {
+		return err
+	}
+
+	expectedProposer := c.GetProposer(block.Height)
+	if block.ProposerId != expectedProposer {
+		return fmt.Errorf("block %d proposed by '%s', expected '%s'", block.Height, block.ProposerId, expectedProposer)
+	}
+
+	publicKey := c.ValidatorSetAt(block.Height).GetPublicKey(block.ProposerId)
+	if !wallet.VerifyHash(proposalSignHash(c.chainId, block), block.ProposerSignature, publicKey) {
+		return fmt.Errorf("invalid proposer signature from '%s'", block.ProposerId)
+	}
+
+	return nil
+}
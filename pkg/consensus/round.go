@@ -0,0 +1,282 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"log/slog"
+	"time"
+)
+
+// Timeouts of the rounds of the next height
+const (
+	ProposeTimeout = 10 * time.Second // No proposal nor heartbeat from the proposer: ask for the next round
+	VoteTimeout    = 3 * time.Second  // The proposer sends its proposal again to the validators that missed it
+	CommitTimeout  = 15 * time.Second // The proposal of the round is not committed: ask for the next round
+)
+
+// Steps of a round: wait for the proposal, vote on it, commit it once a quorum approved it
+var (
+	STEP_PROPOSE = "PROPOSE"
+	STEP_VOTE    = "VOTE"
+	STEP_COMMIT  = "COMMIT"
+)
+
+var (
+	ErrProposalInFlight = errors.New("a proposal is already in flight at this height")
+	ErrRoundProposed    = errors.New("this node already proposed another block in this round")
+)
+
+// view is the state machine of a height in flight: its round, the step of the round and the proposal voted in it.
+// It is dropped when the height is committed, the proposal and votes are reset when the round changes.
+type view struct {
+	height         uint64
+	round          uint32
+	requestedRound uint32
+	step           string
+	stepStart      time.Time
+	lastProgress   time.Time
+	lastResend     time.Time
+	proposal       *pb.Block
+	certificate    *pb.QuorumCertificate      // Quorum reached on the proposal, committed once the parent is
+	votes          map[string]*pb.AVote       // validator -> vote for the proposal
+	viewChanges    map[uint32]map[string]bool // round -> validators asking for it
+}
+
+func newView(height uint64) *view {
+	view := &view{
+		height:      height,
+		viewChanges: make(map[uint32]map[string]bool),
+	}
+	view.enterRound(0)
+
+	return view
+}
+
+// enterRound starts the round at the propose step, the proposal of the previous round is dropped
+func (v *view) enterRound(round uint32) {
+	v.round = round
+	v.proposal = nil
+	v.certificate = nil
+	v.votes = make(map[string]*pb.AVote)
+	v.enterStep(STEP_PROPOSE)
+	v.lastProgress = v.stepStart
+}
+
+func (v *view) enterStep(step string) {
+	v.step = step
+	v.stepStart = time.Now()
+}
+
+// getView returns the state machine of the height, nil for a committed height. Must be called with the lock held.
+func (c *Consensus) getView(height uint64) *view {
+	if height < c.nextHeight {
+		return nil
+	}
+	if c.views[height] == nil {
+		c.views[height] = newView(height)
+	}
+
+	return c.views[height]
+}
+
+// moveToHeight drops the state machines and the WAL entries of the committed heights
+func (c *Consensus) moveToHeight(height uint64) {
+	c.mu.Lock()
+	c.nextHeight = max(c.nextHeight, height)
+	for viewHeight := range c.views {
+		if viewHeight < c.nextHeight {
+			delete(c.views, viewHeight)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.wal.Prune(height - 1); err != nil {
+		slog.Error("Cant prune consensus WAL", "height", height-1, "err", err)
+	}
+}
+
+// dropViewsAbove drops the pipelined heights built on the proposal of the height, it changed round.
+// Must be called with the lock held.
+func (c *Consensus) dropViewsAbove(height uint64) {
+	for viewHeight := range c.views {
+		if viewHeight > height {
+			delete(c.views, viewHeight)
+		}
+	}
+}
+
+// GetRound returns the current round of the height, 0 for a height that is not in flight
+func (c *Consensus) GetRound(height uint64) uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view, ok := c.views[height]
+	if !ok {
+		return 0
+	}
+
+	return view.round
+}
+
+// markProgress restarts the propose timeout of the height
+func (c *Consensus) markProgress(height uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if view := c.getView(height); view != nil {
+		view.lastProgress = time.Now()
+	}
+}
+
+// checkCurrentRound rejects the messages of a committed height, a height beyond the pipeline or another round
+func (c *Consensus) checkCurrentRound(height uint64, round uint32) error {
+	latestBlock, err := c.blockDB.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+	if height <= latestBlock.Height || height > latestBlock.Height+c.pipelineDepth {
+		return fmt.Errorf("stale message for height %d, next height is %d", height, latestBlock.Height+1)
+	}
+
+	if currentRound := c.GetRound(height); round != currentRound {
+		return fmt.Errorf("stale message for round %d of height %d, current round is %d", round, height, currentRound)
+	}
+
+	return nil
+}
+
+// GetProposalBlock returns the proposal in flight at the height, nil when there is none
+func (c *Consensus) GetProposalBlock(height uint64) *pb.Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view, ok := c.views[height]
+	if !ok {
+		return nil
+	}
+
+	return view.proposal
+}
+
+// SetProposalBlock keeps the proposal of the current round and moves the round to the vote step.
+// A round has at most one proposal: another block of the round is rejected.
+func (c *Consensus) SetProposalBlock(block *pb.Block) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view := c.getView(block.Height)
+	if view == nil || block.Round != view.round {
+		return fmt.Errorf("proposal for height %d round %d is not the current round", block.Height, block.Round)
+	}
+	if view.proposal != nil {
+		if !bytes.Equal(view.proposal.CurrentBlockHash, block.CurrentBlockHash) {
+			return ErrProposalInFlight
+		}
+		return nil
+	}
+
+	slog.Info("Store Proposal Block", "height", block.Height, "round", block.Round)
+	slog.Debug("Set proposal block", "block", block)
+
+	// Replayed at restart, the proposal is kept for the round instead of syncing it from the proposer
+	if err := c.writeWAL(WAL_PROPOSAL, block.Height, block.Round, block, nil); err != nil {
+		return err
+	}
+
+	view.proposal = block
+	if view.step == STEP_PROPOSE {
+		view.enterStep(STEP_VOTE)
+	}
+
+	return nil
+}
+
+// rejectProposal moves the round to the vote step without a proposal, it fails at the commit timeout
+// unless the proposer sends a block this node accepts
+func (c *Consensus) rejectProposal(block *pb.Block) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view := c.getView(block.Height)
+	if view != nil && block.Round == view.round && view.step == STEP_PROPOSE {
+		view.enterStep(STEP_VOTE)
+	}
+}
+
+// Tick sends the heartbeat of this node while it is the proposer of the next height and runs the round timeouts
+func (c *Consensus) Tick() (*Output, error) {
+	latestHeight, err := c.blockDB.GetlatestHeight()
+	if err != nil {
+		return nil, err
+	}
+	nextHeight := uint64(latestHeight) + 1
+
+	output, err := c.checkTimeouts(nextHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.GetProposer(nextHeight) == c.nodeId {
+		heartbeat, err := c.createHeartbeat(nextHeight)
+		if err != nil {
+			return nil, err
+		}
+		if output == nil {
+			output = &Output{}
+		}
+		output.Messages = append(output.Messages, heartbeat)
+	}
+
+	return output, nil
+}
+
+// checkTimeouts runs the timeouts of the current round of the next height.
+// The output holds the proposal to send again or the view change to broadcast, nil when nothing timed out.
+func (c *Consensus) checkTimeouts(nextHeight uint64) (*Output, error) {
+	isProposer := c.GetProposer(nextHeight) == c.nodeId
+
+	c.mu.Lock()
+	view := c.getView(nextHeight)
+	if view == nil {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	output := &Output{}
+
+	switch view.step {
+	case STEP_PROPOSE:
+		// The proposer has nothing to wait for, it proposes once it has transactions
+		if isProposer || time.Since(view.lastProgress) < ProposeTimeout {
+			c.mu.Unlock()
+			return nil, nil
+		}
+		slog.Warn("Propose timeout", "height", nextHeight, "round", view.round)
+	case STEP_VOTE:
+		if isProposer && view.proposal != nil && time.Since(view.lastResend) >= VoteTimeout && time.Since(view.stepStart) >= VoteTimeout {
+			view.lastResend = time.Now()
+			output.Proposal = view.proposal
+		}
+		if time.Since(view.stepStart) < CommitTimeout {
+			c.mu.Unlock()
+			return output, nil
+		}
+		slog.Warn("Commit timeout", "height", nextHeight, "round", view.round)
+
+		// Asks again for a higher round if this one is not left after another timeout
+		view.stepStart = time.Now()
+	default:
+		c.mu.Unlock()
+		return nil, nil
+	}
+	c.mu.Unlock()
+
+	viewChange, err := c.requestViewChange(nextHeight)
+	if err != nil {
+		return nil, err
+	}
+	output.Messages = append(output.Messages, viewChange)
+
+	return output, nil
+}
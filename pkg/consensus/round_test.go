@@ -0,0 +1,182 @@
+package consensus
+
+import (
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// findMessage decodes the first message of the type in the output into payload, false when there is none
+func findMessage(t *testing.T, output *Output, msgType string, payload proto.Message) bool {
+	if output == nil {
+		return false
+	}
+	for _, msg := range output.Messages {
+		if msg.Envelope.Type == msgType {
+			if err := decodeMessage(msg.Envelope, payload); err != nil {
+				t.Fatal(err)
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// shiftView moves the clocks of the current view of the engine back, as if the duration had passed
+func shiftView(engine *Consensus, height uint64, duration time.Duration) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	view := engine.getView(height)
+	view.stepStart = view.stepStart.Add(-duration)
+	view.lastProgress = view.lastProgress.Add(-duration)
+	view.lastResend = view.lastResend.Add(-duration)
+}
+
+func TestVotesOfAnotherRoundAreNotCounted(t *testing.T) {
+	genesis, keys := testGenesis(t, 4)
+	network := newTestNetwork(t, genesis, keys, ENGINE_VOTE)
+
+	proposerId := network.engines["node1"].GetProposer(2)
+	proposer := network.engines[proposerId].(*Consensus)
+	latestBlock, _ := network.blockDBs[proposerId].GetLatestBlock()
+	block, err := proposer.CreateBlock(nil, latestBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proposer.Propose(block); err != nil {
+		t.Fatal(err)
+	}
+
+	var votes []*pb.AVote
+	for _, id := range network.receivers(proposerId, "") {
+		output, err := network.engines[id].HandleProposeBlock(block, latestBlock)
+		vote := &pb.AVote{}
+		if err != nil || !findMessage(t, output, MSG_VOTE, vote) || !vote.Approve {
+			t.Fatalf("%s did not approve the proposal: %v", id, err)
+		}
+		votes = append(votes, vote)
+	}
+
+	// The same votes signed for the next round are ignored
+	for _, vote := range votes {
+		stale := proto.Clone(vote).(*pb.AVote)
+		stale.Round = 1
+		if err := signVote(genesis.ChainId, stale, keys[vote.NodeId]); err != nil {
+			t.Fatal(err)
+		}
+		if output, err := proposer.handleVote(stale); err != nil || output != nil {
+			t.Fatalf("vote of round 1 handled in round 0: %v, %v", output, err)
+		}
+	}
+	if height := network.latestHeight(proposerId); height != 1 {
+		t.Fatalf("votes of another round committed height %d", height)
+	}
+
+	var cert *pb.QuorumCertificate
+	for _, vote := range votes {
+		output, err := proposer.handleVote(vote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output != nil && output.Certificate != nil {
+			cert = output.Certificate
+		}
+	}
+	if cert == nil || network.latestHeight(proposerId) != 2 {
+		t.Fatal("votes of the round did not commit the proposal")
+	}
+
+	// A vote of the committed height is stale too
+	if output, err := proposer.handleVote(votes[0]); err != nil || output != nil {
+		t.Fatalf("vote of the committed height handled: %v, %v", output, err)
+	}
+}
+
+func TestProposeTimeoutAsksForNextRound(t *testing.T) {
+	genesis, keys := testGenesis(t, 4)
+	network := newTestNetwork(t, genesis, keys, ENGINE_VOTE)
+	oldProposer := network.engines["node1"].GetProposer(2)
+
+	var viewChanges []*pb.ViewChange
+	for _, id := range network.receivers(oldProposer, "") {
+		engine := network.engines[id].(*Consensus)
+		if output, _ := engine.checkTimeouts(2); output != nil {
+			t.Fatalf("%s timed out before the propose timeout", id)
+		}
+
+		shiftView(engine, 2, ProposeTimeout)
+		output, err := engine.checkTimeouts(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		viewChange := &pb.ViewChange{}
+		if !findMessage(t, output, MSG_VIEW_CHANGE, viewChange) || viewChange.Round != 1 {
+			t.Fatalf("%s did not ask for round 1 after the propose timeout", id)
+		}
+		viewChanges = append(viewChanges, viewChange)
+	}
+
+	// The silent proposer never times out its own round
+	if output, _ := network.engines[oldProposer].(*Consensus).checkTimeouts(2); output != nil {
+		t.Fatal("proposer asked for a view change of its own round")
+	}
+
+	for _, id := range network.ids {
+		engine := network.engines[id].(*Consensus)
+		for _, viewChange := range viewChanges {
+			if _, err := engine.handleViewChange(viewChange, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if round := engine.GetRound(2); round != 1 {
+			t.Fatalf("%s at round %d, want 1", id, round)
+		}
+		if proposer := engine.GetProposer(2); proposer == oldProposer {
+			t.Fatalf("%s still has %s as proposer of round 1", id, proposer)
+		}
+	}
+}
+
+func TestVoteAndCommitTimeouts(t *testing.T) {
+	genesis, keys := testGenesis(t, 4)
+	network := newTestNetwork(t, genesis, keys, ENGINE_VOTE)
+
+	proposerId := network.engines["node1"].GetProposer(2)
+	proposer := network.engines[proposerId].(*Consensus)
+	latestBlock, _ := network.blockDBs[proposerId].GetLatestBlock()
+	block, err := proposer.CreateBlock(nil, latestBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proposer.Propose(block); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proposer.CreateBlock(nil, latestBlock); err != ErrProposalInFlight {
+		t.Fatalf("second block of the round created: %v", err)
+	}
+
+	// No vote comes back: the proposal is sent again at the vote timeout
+	shiftView(proposer, 2, VoteTimeout)
+	output, err := proposer.checkTimeouts(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output == nil || output.Proposal != block || findMessage(t, output, MSG_VIEW_CHANGE, &pb.ViewChange{}) {
+		t.Fatal("proposal not sent again at the vote timeout")
+	}
+
+	// Still not committed at the commit timeout: the round fails
+	shiftView(proposer, 2, CommitTimeout)
+	output, err = proposer.checkTimeouts(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewChange := &pb.ViewChange{}
+	if !findMessage(t, output, MSG_VIEW_CHANGE, viewChange) || viewChange.Round != 1 {
+		t.Fatal("proposer did not ask for round 1 after the commit timeout")
+	}
+}
@@ -0,0 +1,229 @@
+package consensus
+
+import (
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// ValidateTransaction checks a transaction against the current chain state before it enters the mempool or a block
+func ValidateTransaction(blockDB *storage.BlockDB, tx *pb.Transaction) error {
+	return validateTransaction(blockDB, blockDB.NewState(), tx)
+}
+
+// validateTransaction checks the transaction against the chain state with the transactions before it in the block applied
+func validateTransaction(blockDB *storage.BlockDB, state *storage.State, tx *pb.Transaction) error {
+	// A negative amount or fee would move balance from the receiver to the sender, or mint it
+	if !(tx.Amount >= 0) || !(tx.Fee >= 0) || math.IsInf(tx.Amount, 0) || math.IsInf(tx.Fee, 0) {
+		return fmt.Errorf("amount and fee must not be negative")
+	}
+
+	bcTx := util.ConvertToBlockchainTransaction(tx)
+
+	publicKey, err := util.DecodePublicKey(string(tx.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	if !wallet.VerifyTransaction(bcTx, publicKey) {
+		return fmt.Errorf("transaction cant verify")
+	}
+
+	if !wallet.IsSenderPublicKey(bcTx, publicKey) {
+		return fmt.Errorf("public key does not belong to sender")
+	}
+
+	isCommitted, err := blockDB.HasTransaction(bcTx.Hash())
+	if err != nil {
+		return err
+	}
+	if isCommitted {
+		return fmt.Errorf("transaction already committed")
+	}
+
+	committedNonce, err := blockDB.GetAccountNonce(tx.Sender)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce <= committedNonce {
+		return fmt.Errorf("nonce %d already used, committed nonce is %d", tx.Nonce, committedNonce)
+	}
+
+	return validateTransactionState(blockDB, state, tx)
+}
+
+// validateTransactionState checks the sender can spend the amount and owns the stake it unstakes.
+// Balances are only enforced with proof of stake, when the genesis gives them.
+func validateTransactionState(blockDB *storage.BlockDB, state *storage.State, tx *pb.Transaction) error {
+	if tx.Type != blockchain.TX_TRANSFER && !blockDB.IsStakingEnabled() {
+		return fmt.Errorf("staking is not enabled in genesis")
+	}
+	if !blockDB.IsStakingEnabled() {
+		return nil
+	}
+
+	balance, err := state.GetBalance(tx.Sender)
+	if err != nil {
+		return err
+	}
+
+	switch tx.Type {
+	case blockchain.TX_TRANSFER:
+		if balance < tx.Amount+tx.Fee {
+			return fmt.Errorf("insufficient balance: %v, needs %v", balance, tx.Amount+tx.Fee)
+		}
+	case blockchain.TX_STAKE:
+		if tx.ValidatorId == "" || tx.Amount <= 0 {
+			return fmt.Errorf("stake needs a validator id and a positive amount")
+		}
+
+		stake, err := state.GetStake(tx.ValidatorId)
+		if err != nil {
+			return err
+		}
+		if stake == nil && tx.ValidatorAddress == "" {
+			return fmt.Errorf("new validator %s needs an address", tx.ValidatorId)
+		}
+		if stake != nil && stake.Owner != string(tx.Sender) {
+			return fmt.Errorf("validator %s is staked by another account", tx.ValidatorId)
+		}
+
+		if balance < tx.Amount+tx.Fee {
+			return fmt.Errorf("insufficient balance: %v, needs %v", balance, tx.Amount+tx.Fee)
+		}
+	case blockchain.TX_UNSTAKE:
+		stake, err := state.GetStake(tx.ValidatorId)
+		if err != nil {
+			return err
+		}
+		if stake == nil || stake.Owner != string(tx.Sender) {
+			return fmt.Errorf("validator %s is not staked by the sender", tx.ValidatorId)
+		}
+		if tx.Amount <= 0 || tx.Amount > stake.Amount {
+			return fmt.Errorf("unstake amount must be positive and at most the stake %v", stake.Amount)
+		}
+
+		if balance < tx.Fee {
+			return fmt.Errorf("insufficient balance for the fee: %v", tx.Fee)
+		}
+	default:
+		return fmt.Errorf("unknown transaction type: %s", tx.Type)
+	}
+
+	return nil
+}
+
+// pendingContext is the chain state a block is validated against: the committed chain with the pending blocks
+// it extends applied on top, there are pending blocks only when pipelining
+type pendingContext struct {
+	state       *storage.State
+	nonces      map[string]uint64 // Highest nonce of each sender in the pending blocks
+	evidenceIds map[string]bool   // evidence already in the pending blocks
+}
+
+func newPendingContext(blockDB *storage.BlockDB, pendingBlocks []*pb.Block) (*pendingContext, error) {
+	ctx := &pendingContext{
+		state:       blockDB.NewState(),
+		nonces:      make(map[string]uint64),
+		evidenceIds: make(map[string]bool),
+	}
+
+	// Same order as SaveBlock: transactions, evidence, then the end of the epoch
+	for _, block := range pendingBlocks {
+		for _, tx := range block.Transactions {
+			if err := ctx.state.ApplyTransaction(util.ConvertToBlockchainTransaction(tx)); err != nil {
+				return nil, err
+			}
+			ctx.nonces[string(tx.Sender)] = max(ctx.nonces[string(tx.Sender)], tx.Nonce)
+		}
+		for _, evidence := range block.Evidence {
+			if err := ctx.state.ApplyEvidence(evidence, block.Height); err != nil {
+				return nil, err
+			}
+			ctx.evidenceIds[evidenceId(evidence)] = true
+		}
+		if err := ctx.state.ApplyEpochEnd(block.Height); err != nil {
+			return nil, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// nextNonce is the only nonce the next transaction of the sender can have on top of the context
+func (ctx *pendingContext) nextNonce(blockDB *storage.BlockDB, sender []byte) (uint64, error) {
+	if nonce, ok := ctx.nonces[string(sender)]; ok {
+		return nonce + 1, nil
+	}
+
+	committedNonce, err := blockDB.GetAccountNonce(sender)
+	if err != nil {
+		return 0, err
+	}
+
+	return committedNonce + 1, nil
+}
+
+// validateBlockTransactions checks the transactions of a block extending the chain of the context.
+// The nonces of a sender follow each other from its committed nonce, without gap or duplicate.
+func validateBlockTransactions(blockDB *storage.BlockDB, ctx *pendingContext, txs []*pb.Transaction) error {
+	for _, tx := range txs {
+		nextNonce, err := ctx.nextNonce(blockDB, tx.Sender)
+		if err != nil {
+			return err
+		}
+		if tx.Nonce != nextNonce {
+			return fmt.Errorf("nonce %d of sender %s, expected %d", tx.Nonce, tx.Sender, nextNonce)
+		}
+
+		if err := validateTransaction(blockDB, ctx.state, tx); err != nil {
+			return err
+		}
+		if err := ctx.state.ApplyTransaction(util.ConvertToBlockchainTransaction(tx)); err != nil {
+			return err
+		}
+		ctx.nonces[string(tx.Sender)] = tx.Nonce
+	}
+
+	return nil
+}
+
+// SelectTransactions keeps the pending transactions that are valid together in a block.
+// A sender can have pending transactions spending more than its balance or after a missing nonce, they wait.
+func SelectTransactions(blockDB *storage.BlockDB, pendingTransactions []*pb.Transaction) []*pb.Transaction {
+	ctx, _ := newPendingContext(blockDB, nil)
+	return selectTransactions(blockDB, ctx, pendingTransactions)
+}
+
+// selectTransactions keeps the transactions valid together on top of the chain of the context,
+// ordered by sender and nonce whatever order they arrived in
+func selectTransactions(blockDB *storage.BlockDB, ctx *pendingContext, pendingTransactions []*pb.Transaction) []*pb.Transaction {
+	pendingTransactions = slices.Clone(pendingTransactions)
+	blockchain.SortBySenderNonce(pendingTransactions)
+
+	var selected []*pb.Transaction
+	for _, tx := range pendingTransactions {
+		nextNonce, err := ctx.nextNonce(blockDB, tx.Sender)
+		if err != nil || tx.Nonce != nextNonce {
+			continue
+		}
+		if err := validateTransaction(blockDB, ctx.state, tx); err != nil {
+			slog.Debug("Skip transaction for the block", "err", err)
+			continue
+		}
+		if err := ctx.state.ApplyTransaction(util.ConvertToBlockchainTransaction(tx)); err != nil {
+			continue
+		}
+
+		ctx.nonces[string(tx.Sender)] = tx.Nonce
+		selected = append(selected, tx)
+	}
+
+	return selected
+}
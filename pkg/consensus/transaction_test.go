@@ -0,0 +1,106 @@
+package consensus
+
+import (
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValidateTransactionRejectsNegativeAmounts(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+
+	genesis, _ := testGenesis(t, 1)
+	genesis.Pos = &types.PosConfig{EpochLength: 10}
+	genesis.Balances = map[string]float64{string(alice.address): 100}
+	blockDB := newTestBlockDB(t, genesis)
+
+	transactions := map[string]*blockchain.Transaction{
+		"negative amount":  {Receiver: bob.address, Amount: -100, Nonce: 1},
+		"negative fee":     {Receiver: bob.address, Amount: 1, Fee: -50, Nonce: 1},
+		"NaN amount":       {Receiver: bob.address, Amount: math.NaN(), Nonce: 1},
+		"infinite fee":     {Receiver: bob.address, Amount: 1, Fee: math.Inf(1), Nonce: 1},
+		"negative stake":   {Type: blockchain.TX_STAKE, ValidatorId: "node1", Amount: -1, Nonce: 1},
+		"unstake, fee < 0": {Type: blockchain.TX_UNSTAKE, ValidatorId: "node1", Amount: 1, Fee: -1, Nonce: 1},
+	}
+	for name, tx := range transactions {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateTransaction(blockDB, alice.sign(tx))
+			if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+				t.Fatalf("got %v, want the negative amount rejected", err)
+			}
+		})
+	}
+
+	// Without staking balances are not checked, amounts still are
+	genesis, _ = testGenesis(t, 1)
+	blockDB = newTestBlockDB(t, genesis)
+	if err := ValidateTransaction(blockDB, alice.sign(&blockchain.Transaction{Receiver: bob.address, Amount: -1, Nonce: 1})); err == nil {
+		t.Fatal("negative amount accepted without staking")
+	}
+	if err := ValidateTransaction(blockDB, alice.sign(&blockchain.Transaction{Receiver: bob.address, Amount: 0, Nonce: 1})); err != nil {
+		t.Fatalf("zero amount (cancel) rejected: %v", err)
+	}
+}
+
+func TestNoncesArrivingOutOfOrder(t *testing.T) {
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	genesis, _ := testGenesis(t, 1)
+	blockDB := newTestBlockDB(t, genesis)
+	transfer := func(nonce uint64) *pb.Transaction {
+		return alice.sign(&blockchain.Transaction{Receiver: bob.address, Amount: 1, Nonce: nonce})
+	}
+
+	// Nonce 2 arrives before 1 and nonce 4 without 3, the mempool takes them all
+	first, second, fourth := transfer(1), transfer(2), transfer(4)
+	arrived := []*pb.Transaction{second, fourth, first}
+	for _, tx := range arrived {
+		if err := ValidateTransaction(blockDB, tx); err != nil {
+			t.Fatalf("nonce %d rejected: %v", tx.Nonce, err)
+		}
+	}
+
+	selected := SelectTransactions(blockDB, arrived)
+	if len(selected) != 2 || selected[0].Nonce != 1 || selected[1].Nonce != 2 {
+		t.Fatalf("selected %d transactions, want nonces 1 and 2 in order", len(selected))
+	}
+
+	// A block follows the committed nonce of each sender without gap
+	invalidBlocks := map[string][]*pb.Transaction{
+		"gap":           {first, fourth},
+		"out of order":  {second, first},
+		"duplicate":     {first, first},
+		"missing first": {second},
+	}
+	for name, txs := range invalidBlocks {
+		ctx, _ := newPendingContext(blockDB, nil)
+		if err := validateBlockTransactions(blockDB, ctx, txs); err == nil {
+			t.Fatalf("block with nonces %s accepted", name)
+		}
+	}
+	ctx, _ := newPendingContext(blockDB, nil)
+	if err := validateBlockTransactions(blockDB, ctx, selected); err != nil {
+		t.Fatal(err)
+	}
+
+	var transactions []*blockchain.Transaction
+	for _, tx := range selected {
+		transactions = append(transactions, util.ConvertToBlockchainTransaction(tx))
+	}
+	latestBlock, _ := blockDB.GetLatestBlock()
+	if err := blockDB.SaveBlock(blockchain.NewBlock(transactions, latestBlock, "node1", 0), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nonce 4 waits until 3 arrives
+	if selected := SelectTransactions(blockDB, []*pb.Transaction{fourth}); len(selected) != 0 {
+		t.Fatal("nonce 4 selected without nonce 3")
+	}
+	selected = SelectTransactions(blockDB, []*pb.Transaction{fourth, transfer(3)})
+	if len(selected) != 2 || selected[0].Nonce != 3 || selected[1].Nonce != 4 {
+		t.Fatalf("selected %d transactions, want nonces 3 and 4 in order", len(selected))
+	}
+}
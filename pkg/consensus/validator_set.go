@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+)
+
+type ValidatorSet struct {
+	validators []types.Validator
+	indexById  map[string]int
+	publicKeys map[string]*ecdsa.PublicKey
+	powers     map[string]float64
+	totalPower float64
+}
+
+func NewValidatorSet(validators []types.Validator) *ValidatorSet {
+	validatorSet := &ValidatorSet{
+		validators: validators,
+		indexById:  make(map[string]int),
+		publicKeys: make(map[string]*ecdsa.PublicKey),
+		powers:     make(map[string]float64),
+	}
+
+	for i, validator := range validators {
+		validatorSet.indexById[validator.Id] = i
+
+		// Keys are checked when the genesis is loaded
+		publicKey, _ := util.DecodePublicKey(validator.PublicKey)
+		validatorSet.publicKeys[validator.Id] = publicKey
+
+		// Voting power is the stake, validators without stake count as 1
+		power := validator.Stake
+		if power <= 0 {
+			power = 1
+		}
+		validatorSet.powers[validator.Id] = power
+		validatorSet.totalPower += power
+	}
+
+	return validatorSet
+}
+
+func (v *ValidatorSet) Size() int {
+	return len(v.validators)
+}
+
+func (v *ValidatorSet) Power(id string) float64 {
+	return v.powers[id]
+}
+
+func (v *ValidatorSet) TotalPower() float64 {
+	return v.totalPower
+}
+
+// PowerOf sums the voting power of the validators, unknown and repeated ids are not counted
+func (v *ValidatorSet) PowerOf(ids []string) float64 {
+	counted := make(map[string]bool)
+	power := 0.0
+	for _, id := range ids {
+		if counted[id] {
+			continue
+		}
+		counted[id] = true
+		power += v.powers[id]
+	}
+
+	return power
+}
+
+// HasQuorum tells if the validators hold more than 2/3 of the voting power, the power needed to commit.
+// With equal powers it is 2f+1 validators when n = 3f+1 (4, 7, 10 ...) and stays safe for the other sizes (e.g. 3 of 3).
+func (v *ValidatorSet) HasQuorum(ids []string) bool {
+	return 3*v.PowerOf(ids) > 2*v.totalPower
+}
+
+// HasOneHonest tells if the validators hold at least 1/3 of the voting power, more than the byzantine validators
+// the set tolerates: at least one of them is honest (f+1 validators with equal powers).
+func (v *ValidatorSet) HasOneHonest(ids []string) bool {
+	return 3*v.PowerOf(ids) >= v.totalPower
+}
+
+func (v *ValidatorSet) Has(id string) bool {
+	_, ok := v.indexById[id]
+	return ok
+}
+
+func (v *ValidatorSet) Get(id string) (types.Validator, bool) {
+	index, ok := v.indexById[id]
+	if !ok {
+		return types.Validator{}, false
+	}
+
+	return v.validators[index], true
+}
+
+func (v *ValidatorSet) GetPublicKey(id string) *ecdsa.PublicKey {
+	return v.publicKeys[id]
+}
+
+func (v *ValidatorSet) Validators() []types.Validator {
+	return v.validators
+}
+
+// Proposer returns the validator proposing the block at this height and round (round-robin), none in an empty set
+func (v *ValidatorSet) Proposer(height uint64, round uint32) types.Validator {
+	if v.Size() == 0 {
+		return types.Validator{}
+	}
+
+	return v.validators[(height+uint64(round))%uint64(v.Size())]
+}
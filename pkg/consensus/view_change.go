@@ -0,0 +1,169 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/wallet"
+	"log/slog"
+	"time"
+)
+
+// The proposer of the next height sends a heartbeat every HeartbeatInterval
+const HeartbeatInterval = 1 * time.Second
+
+// viewSignHash is the hash signed for heartbeats and view changes: (kind, chain id, height, round)
+func viewSignHash(kind string, chainId string, height uint64, round uint32) []byte {
+	data := []byte(kind)
+	data = binary.AppendUvarint(data, uint64(len(chainId)))
+	data = append(data, chainId...)
+	data = binary.BigEndian.AppendUint64(data, height)
+	data = binary.BigEndian.AppendUint32(data, round)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// createHeartbeat returns the heartbeat of this node as proposer of the height, broadcast to every peer
+func (c *Consensus) createHeartbeat(height uint64) (*Message, error) {
+	heartbeat := &pb.Heartbeat{
+		NodeId: c.nodeId,
+		Height: height,
+		Round:  c.GetRound(height),
+	}
+
+	signature, err := wallet.SignHash(viewSignHash("heartbeat", c.chainId, heartbeat.Height, heartbeat.Round), c.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	heartbeat.Signature = signature
+
+	return newMessage("", MSG_HEARTBEAT, heartbeat)
+}
+
+// handleHeartbeat restarts the leader timeout when the heartbeat comes from the current proposer
+func (c *Consensus) handleHeartbeat(heartbeat *pb.Heartbeat, nextHeight uint64) error {
+	publicKey := c.ValidatorSetAt(heartbeat.Height).GetPublicKey(heartbeat.NodeId)
+	if !wallet.VerifyHash(viewSignHash("heartbeat", c.chainId, heartbeat.Height, heartbeat.Round), heartbeat.Signature, publicKey) {
+		return fmt.Errorf("invalid heartbeat signature from '%s'", heartbeat.NodeId)
+	}
+
+	if heartbeat.Height != nextHeight || heartbeat.Round != c.GetRound(nextHeight) || heartbeat.NodeId != c.GetProposer(nextHeight) {
+		slog.Debug("Ignore heartbeat not from the current proposer", "heartbeat", heartbeat)
+		return nil
+	}
+
+	c.markProgress(nextHeight)
+
+	return nil
+}
+
+func (c *Consensus) createViewChange(height uint64, round uint32) (*pb.ViewChange, error) {
+	viewChange := &pb.ViewChange{
+		NodeId: c.nodeId,
+		Height: height,
+		Round:  round,
+	}
+
+	signature, err := wallet.SignHash(viewSignHash("view-change", c.chainId, height, round), c.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	viewChange.Signature = signature
+
+	return viewChange, nil
+}
+
+// requestViewChange signs a view change for the round after the highest one already asked, so a dead next proposer is skipped too.
+// It returns the view change to broadcast.
+func (c *Consensus) requestViewChange(nextHeight uint64) (*Message, error) {
+	c.mu.Lock()
+	view := c.getView(nextHeight)
+	if view == nil {
+		c.mu.Unlock()
+		return nil, nil
+	}
+	newRound := max(view.round, view.requestedRound) + 1
+	view.requestedRound = newRound
+	view.lastProgress = time.Now()
+	c.mu.Unlock()
+
+	slog.Warn("Asking for a view change", "height", nextHeight, "round", newRound, "proposer", c.GetProposer(nextHeight))
+
+	viewChange, err := c.createViewChange(nextHeight, newRound)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.handleViewChange(viewChange, nextHeight); err != nil {
+		return nil, err
+	}
+
+	return newMessage("", MSG_VIEW_CHANGE, viewChange)
+}
+
+// handleViewChange records a view change and moves to its round once a quorum asks for it.
+// When f+1 validators ask for a higher round this node joins them, the output holds its own view change to broadcast.
+func (c *Consensus) handleViewChange(viewChange *pb.ViewChange, nextHeight uint64) (*Output, error) {
+	validatorSet := c.ValidatorSetAt(viewChange.Height)
+	publicKey := validatorSet.GetPublicKey(viewChange.NodeId)
+	if !wallet.VerifyHash(viewSignHash("view-change", c.chainId, viewChange.Height, viewChange.Round), viewChange.Signature, publicKey) {
+		return nil, fmt.Errorf("invalid view change signature from '%s'", viewChange.NodeId)
+	}
+
+	c.mu.Lock()
+	view := c.getView(nextHeight)
+	if view == nil || viewChange.Height != view.height || viewChange.Round <= view.round {
+		c.mu.Unlock()
+		slog.Debug("Ignore stale view change", "viewChange", viewChange)
+		return nil, nil
+	}
+
+	if view.viewChanges[viewChange.Round] == nil {
+		view.viewChanges[viewChange.Round] = make(map[string]bool)
+	}
+	view.viewChanges[viewChange.Round][viewChange.NodeId] = true
+	var requesters []string
+	for nodeId := range view.viewChanges[viewChange.Round] {
+		requesters = append(requesters, nodeId)
+	}
+
+	if validatorSet.HasQuorum(requesters) {
+		view.enterRound(viewChange.Round)
+		c.dropViewsAbove(view.height)
+		c.mu.Unlock()
+
+		if err := c.writeWAL(WAL_ROUND, view.height, viewChange.Round, nil, nil); err != nil {
+			return nil, err
+		}
+
+		slog.Warn("View change: moved to new round", "height", view.height, "round", viewChange.Round, "proposer", c.GetProposer(view.height))
+		return nil, nil
+	}
+
+	isJoining := validatorSet.HasOneHonest(requesters) && view.requestedRound < viewChange.Round
+	if isJoining {
+		view.requestedRound = viewChange.Round
+	}
+	c.mu.Unlock()
+
+	if !isJoining {
+		return nil, nil
+	}
+
+	joinViewChange, err := c.createViewChange(viewChange.Height, viewChange.Round)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.handleViewChange(joinViewChange, nextHeight); err != nil {
+		return nil, err
+	}
+
+	message, err := newMessage("", MSG_VIEW_CHANGE, joinViewChange)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{Messages: []*Message{message}}, nil
+}
@@ -0,0 +1,40 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/wallet"
+)
+
+// voteSignHash is the hash signed by a validator: (chain id, height, block hash, approve, round)
+func voteSignHash(chainId string, vote *pb.AVote) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(chainId)))
+	data = append(data, chainId...)
+	data = binary.BigEndian.AppendUint64(data, vote.BlockHeight)
+	data = append(data, vote.BlockHash...)
+	if vote.Approve {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+	data = binary.BigEndian.AppendUint32(data, vote.Round)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func signVote(chainId string, vote *pb.AVote, privKey *ecdsa.PrivateKey) error {
+	signature, err := wallet.SignHash(voteSignHash(chainId, vote), privKey)
+	if err != nil {
+		return err
+	}
+
+	vote.Signature = signature
+	return nil
+}
+
+func verifyVote(chainId string, vote *pb.AVote, pubKey *ecdsa.PublicKey) bool {
+	return wallet.VerifyHash(voteSignHash(chainId, vote), vote.Signature, pubKey)
+}
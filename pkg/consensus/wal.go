@@ -0,0 +1,179 @@
+package consensus
+
+import (
+	"bytes"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"log/slog"
+)
+
+// Entries of the consensus write-ahead log
+var (
+	WAL_PROPOSAL = "PROPOSAL" // Proposal held for the round, created by this node or accepted from the proposer
+	WAL_OWN_VOTE = "OWN_VOTE" // Vote signed by this node
+	WAL_VOTE     = "VOTE"     // Vote received by the proposer
+	WAL_ROUND    = "ROUND"    // Round entered by view change
+
+	WAL_PBFT_MESSAGE = "PBFT_MESSAGE" // Prepare / commit in the log of its height and view, signed by this node or received
+	WAL_PBFT_LOCK    = "PBFT_LOCK"    // Block prepared by this node, later views only prepare its transactions
+)
+
+// writeWAL persists the entry, the node must not send what it records when it fails
+func (c *Consensus) writeWAL(entryType string, height uint64, round uint32, proposal *pb.Block, vote *pb.AVote) error {
+	return c.wal.Append(&pb.WalEntry{
+		Type:     entryType,
+		Height:   height,
+		Round:    round,
+		Proposal: proposal,
+		Vote:     vote,
+	})
+}
+
+// replayWAL restores the rounds in flight before a restart: their round, proposal and received votes,
+// and what this node signed so it does not sign another block in the same round.
+// Each entry goes to replay, the replayEntry of the engine.
+func (c *Consensus) replayWAL(replay func(entry *pb.WalEntry)) error {
+	latestBlock, err := c.blockDB.GetLatestBlock()
+	if err != nil {
+		return err
+	}
+	c.moveToHeight(latestBlock.Height + 1)
+
+	entries, err := c.wal.Load()
+	if err != nil {
+		return err
+	}
+
+	replayed := 0
+	for _, entry := range entries {
+		if entry.Height <= latestBlock.Height {
+			continue
+		}
+		replay(entry)
+		replayed++
+	}
+
+	if replayed > 0 {
+		slog.Info("Replayed consensus WAL", "entries", replayed, "nextHeight", latestBlock.Height+1)
+	}
+
+	return nil
+}
+
+func (c *Consensus) replayEntry(entry *pb.WalEntry) {
+	switch entry.Type {
+	case WAL_PROPOSAL:
+		block := entry.Proposal
+		if block == nil {
+			return
+		}
+		if block.ProposerId == c.nodeId {
+			c.signOnce("proposal", block.Height, block.Round, block.CurrentBlockHash)
+		}
+		c.recordProposal(block)
+
+		c.mu.Lock()
+		view := c.enterReplayedRound(entry.Height, block.Round)
+		if view != nil && view.round == block.Round && view.proposal == nil {
+			view.proposal = block
+			view.enterStep(STEP_VOTE)
+		}
+		c.mu.Unlock()
+	case WAL_OWN_VOTE:
+		if entry.Vote != nil && entry.Vote.Approve {
+			c.signOnce("vote", entry.Height, entry.Round, entry.Vote.BlockHash)
+		}
+	case WAL_VOTE:
+		vote := entry.Vote
+		if vote == nil {
+			return
+		}
+		c.recordVote(vote)
+
+		c.mu.Lock()
+		view := c.getView(entry.Height)
+		if view != nil && view.proposal != nil && view.round == vote.Round && bytes.Equal(view.proposal.CurrentBlockHash, vote.BlockHash) {
+			view.votes[vote.NodeId] = vote
+		}
+		c.mu.Unlock()
+	case WAL_ROUND:
+		c.mu.Lock()
+		c.enterReplayedRound(entry.Height, entry.Round)
+		c.mu.Unlock()
+	default:
+		slog.Warn("Skip unknown consensus WAL entry", "type", entry.Type)
+	}
+}
+
+// enterReplayedRound moves the height to the round when it is higher, as the view change did before the restart.
+// Must be called with the lock held.
+func (c *Consensus) enterReplayedRound(height uint64, round uint32) *view {
+	view := c.getView(height)
+	if view != nil && round > view.round {
+		view.enterRound(round)
+		view.requestedRound = max(view.requestedRound, round)
+		c.dropViewsAbove(height)
+	}
+
+	return view
+}
+
+// writePbftWAL persists a prepare / commit before this node sends it or counts it
+func (p *PBFT) writePbftWAL(msg *pb.PbftMessage) error {
+	return p.wal.Append(&pb.WalEntry{
+		Type:    WAL_PBFT_MESSAGE,
+		Height:  msg.Height,
+		Round:   msg.View,
+		Message: msg,
+	})
+}
+
+// replayEntry restores the PBFT logs and locks of the heights in flight, the other entries are the ones of Consensus.
+// Nothing is sent nor committed during the replay, the next message of the height moves its log on.
+func (p *PBFT) replayEntry(entry *pb.WalEntry) {
+	switch entry.Type {
+	case WAL_PBFT_MESSAGE:
+		msg := entry.Message
+		if msg == nil {
+			return
+		}
+		if msg.NodeId == p.nodeId {
+			p.signOnce(msg.Type, msg.Height, msg.View, msg.BlockHash)
+		} else {
+			p.recordPbftMessage(msg)
+		}
+
+		p.pbftMu.Lock()
+		defer p.pbftMu.Unlock()
+
+		log := p.getLog(msg.Height, msg.View)
+		switch msg.Type {
+		case PBFT_PREPARE:
+			log.prepares[msg.NodeId] = msg.BlockHash
+			// The pre-prepare this node prepared, the proposal entry before holds the block
+			if proposal := p.GetProposalBlock(msg.Height); msg.NodeId == p.nodeId && log.block == nil && proposal != nil &&
+				proposal.Round == msg.View && bytes.Equal(proposal.CurrentBlockHash, msg.BlockHash) {
+				log.block = proposal
+				log.prepares[proposal.ProposerId] = proposal.CurrentBlockHash
+			}
+		case PBFT_COMMIT:
+			log.commits[msg.NodeId] = msg
+		}
+	case WAL_PBFT_LOCK:
+		block := entry.Proposal
+		if block == nil {
+			return
+		}
+
+		p.pbftMu.Lock()
+		defer p.pbftMu.Unlock()
+
+		log := p.getLog(block.Height, block.Round)
+		if log.block == nil {
+			log.block = block
+		}
+		log.isPrepared = true
+		p.lockedBlocks[block.Height] = block
+	default:
+		p.Consensus.replayEntry(entry)
+	}
+}
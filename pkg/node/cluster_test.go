@@ -0,0 +1,196 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/consensus"
+	"go-blockchain-ber1/pkg/p2p"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// testNode is a validator of the cluster wired as cmd/node does, on a store in memory
+type testNode struct {
+	node    *Node
+	server  interface{ Stop() }
+	blockDB *storage.BlockDB
+	client  pb.BlockchainClient
+	stopped bool
+}
+
+// testCluster runs the validators of the genesis in process, talking grpc to each other on local ports
+type testCluster struct {
+	t       testing.TB
+	genesis *types.Genesis
+	ids     []string
+	nodes   map[string]*testNode
+}
+
+// newTestCluster starts n validators node1..nodeN, their addresses are picked by the system
+func newTestCluster(t testing.TB, n int, engineName string, blockProduction *types.BlockProductionConfig) *testCluster {
+	genesis := &types.Genesis{ChainId: "test", ConsensusEngine: engineName, BlockProduction: blockProduction}
+	keys := make(map[string]*ecdsa.PrivateKey)
+	listeners := make(map[string]net.Listener)
+	for i := 1; i <= n; i++ {
+		privateKey, err := wallet.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id := fmt.Sprintf("node%d", i)
+		keys[id] = privateKey
+		listeners[id] = listener
+		genesis.Validators = append(genesis.Validators, types.Validator{
+			Id:        id,
+			Address:   listener.Addr().String(),
+			PublicKey: util.EncodePublicKey(privateKey),
+		})
+	}
+
+	cluster := &testCluster{t: t, genesis: genesis, nodes: make(map[string]*testNode)}
+	t.Cleanup(cluster.stopAll)
+	for _, validator := range genesis.Validators {
+		cluster.ids = append(cluster.ids, validator.Id)
+		cluster.nodes[validator.Id] = cluster.startNode(validator.Id, keys[validator.Id], listeners[validator.Id])
+	}
+	// Every peer listens before a node syncs with them
+	for _, id := range cluster.ids {
+		cluster.nodes[id].node.Init()
+	}
+
+	return cluster
+}
+
+func (c *testCluster) startNode(nodeId string, privateKey *ecdsa.PrivateKey, listener net.Listener) *testNode {
+	store := storage.NewMemoryStore()
+	blockDB := newTestBlockDB(store, c.genesis)
+	memPool := blockchain.NewMemPool(storage.NewMemPoolDB(store))
+
+	validatorSet := consensus.NewValidatorSet(c.genesis.Validators)
+	engine, err := consensus.NewEngine(c.genesis.ConsensusEngine, c.genesis, blockDB, storage.NewWalDB(store), validatorSet, nodeId, privateKey)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	peerManager := p2p.NewPeerManager(validatorSet.Validators())
+	for _, validator := range c.genesis.Validators {
+		if validator.Id != nodeId {
+			peerManager.AddPeer(validator.Address)
+		}
+	}
+	go peerManager.RunTransactionGossip()
+
+	server := p2p.NewGRPCServer(blockDB, peerManager, memPool, engine, nodeId)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() { conn.Close() })
+
+	return &testNode{
+		node:    NewNode(peerManager, blockDB, memPool, engine, nodeId, c.genesis.BlockProduction),
+		server:  server,
+		blockDB: blockDB,
+		client:  pb.NewBlockchainClient(conn),
+	}
+}
+
+// stop kills the validator: it stops producing blocks and answering its peers
+func (c *testCluster) stop(nodeId string) {
+	node := c.nodes[nodeId]
+	if node.stopped {
+		return
+	}
+
+	node.stopped = true
+	node.node.Stop()
+	node.server.Stop()
+}
+
+func (c *testCluster) stopAll() {
+	for _, id := range c.ids {
+		c.stop(id)
+	}
+}
+
+// running are the validators not stopped
+func (c *testCluster) running() []*testNode {
+	var nodes []*testNode
+	for _, id := range c.ids {
+		if !c.nodes[id].stopped {
+			nodes = append(nodes, c.nodes[id])
+		}
+	}
+
+	return nodes
+}
+
+// submit sends the transaction to the first running validator, which gossips it to the others
+func (c *testCluster) submit(tx *pb.Transaction) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	node := c.running()[0]
+	if _, err := node.client.SendTransaction(ctx, tx); err != nil {
+		c.t.Fatalf("%s rejected the transaction: %v", node.node.NodeId, err)
+	}
+}
+
+// waitUntil waits until the condition holds on every running validator
+func (c *testCluster) waitUntil(timeout time.Duration, condition string, holds func(node *testNode) bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var waiting []string
+		for _, node := range c.running() {
+			if !holds(node) {
+				waiting = append(waiting, node.node.NodeId)
+			}
+		}
+		if len(waiting) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			c.t.Fatalf("%v not %s after %s", waiting, condition, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitHeight waits until every running validator committed the height
+func (c *testCluster) waitHeight(height uint64, timeout time.Duration) {
+	c.waitUntil(timeout, fmt.Sprintf("at height %d", height), func(node *testNode) bool {
+		return node.latestHeight() >= height
+	})
+}
+
+// proposer is the validator proposing the next height as the first running validator sees it
+func (c *testCluster) proposer() string {
+	node := c.running()[0]
+	return node.node.consensus.GetProposer(node.latestHeight() + 1)
+}
+
+func (n *testNode) latestHeight() uint64 {
+	latestHeight, err := n.blockDB.GetlatestHeight()
+	if err != nil {
+		return 0
+	}
+
+	return uint64(latestHeight)
+}
@@ -0,0 +1,50 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"testing"
+)
+
+// newTestBlockDB is a block database on the store, initialized with the genesis
+func newTestBlockDB(store storage.Store, genesis *types.Genesis) *storage.BlockDB {
+	blockDB := storage.NewBlockDB(store)
+	blockDB.Init(genesis)
+
+	return blockDB
+}
+
+// testAccount signs the transactions of a sender, nonce after nonce
+type testAccount struct {
+	privateKey *ecdsa.PrivateKey
+	address    []byte
+	nonce      uint64
+}
+
+func newTestAccount(t testing.TB) *testAccount {
+	privateKey, err := wallet.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testAccount{
+		privateKey: privateKey,
+		address:    wallet.PublicKeyToAddress(&privateKey.PublicKey),
+	}
+}
+
+// transfer returns the next transaction of the account as a client sends it
+func (a *testAccount) transfer(receiver string, amount float64) *pb.Transaction {
+	a.nonce++
+	tx := &blockchain.Transaction{Sender: a.address, Receiver: []byte(receiver), Amount: amount, Nonce: a.nonce}
+	wallet.SignTransaction(tx, a.privateKey)
+
+	pbTx := util.ConvertToPbTransaction(tx)
+	pbTx.PublicKey = []byte(util.EncodePublicKey(a.privateKey))
+	return pbTx
+}
@@ -0,0 +1,59 @@
+package node
+
+import (
+	"bytes"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"testing"
+)
+
+func TestRestartReplaysPendingTransactions(t *testing.T) {
+	store := storage.NewMemoryStore()
+	validator := newTestAccount(t)
+	blockDB := newTestBlockDB(store, &types.Genesis{
+		ChainId:    "test",
+		Validators: []types.Validator{{Id: "node1", PublicKey: util.EncodePublicKey(validator.privateKey)}},
+	})
+	memPool := blockchain.NewMemPool(storage.NewMemPoolDB(store))
+
+	alice := newTestAccount(t)
+	committed := alice.transfer("bob", 1)
+	pending := alice.transfer("bob", 2)
+	forged := alice.transfer("bob", 3)
+	forged.Amount = 300
+	for _, tx := range []*pb.Transaction{committed, pending, forged} {
+		if _, err := memPool.AddPendingTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The node stops after committing the first transaction, before its mempool is pruned
+	latestBlock, err := blockDB.GetLatestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := blockchain.NewBlock([]*blockchain.Transaction{util.ConvertToBlockchainTransaction(committed)}, latestBlock, "node1", 0)
+	if err := blockDB.SaveBlock(block, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := &Node{blockDB: blockDB, memPool: blockchain.NewMemPool(storage.NewMemPoolDB(store))}
+	restarted.restoreMemPool()
+
+	pendingTransactions := restarted.memPool.GetAllPendingTransactions()
+	if len(pendingTransactions) != 1 || !bytes.Equal(pendingTransactions[0].Signature, pending.Signature) {
+		t.Fatalf("%d pending transactions after the restart, want only the valid uncommitted one", len(pendingTransactions))
+	}
+
+	// The dropped transactions are deleted from the database too
+	count, err := blockchain.NewMemPool(storage.NewMemPoolDB(store)).Restore(func(tx *pb.Transaction) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("%d transactions persisted after the restart, want 1", count)
+	}
+}
@@ -2,56 +2,105 @@ package node
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go-blockchain-ber1/pkg/blockchain"
 	"go-blockchain-ber1/pkg/consensus"
 	"go-blockchain-ber1/pkg/p2p"
 	"go-blockchain-ber1/pkg/p2p/pb"
 	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
 	"go-blockchain-ber1/pkg/util"
 	"log/slog"
 	"time"
+
+	"google.golang.org/protobuf/proto"
 )
 
+// Defaults of the block production: a block every 5 seconds when there are pending transactions
+var defaultBlockProduction = types.BlockProductionConfig{
+	BlockInterval: 5,
+}
+
+// The task queue checks every blockProductionTick if the block interval elapsed
+const blockProductionTick = 200 * time.Millisecond
+
 type Node struct {
 	peerManager *p2p.PeerManager
 	blockDB     *storage.BlockDB
 	memPool     *blockchain.MemPool
-	consensus   *consensus.Consensus
+	consensus   consensus.Engine
+
+	blockProduction types.BlockProductionConfig
+
+	// When this node saw the chain reach its latest height, the next block is due a block interval later
+	latestHeight   uint64
+	latestHeightAt time.Time
 
-	IsLeader bool
-	NodeId   string
+	// Closed by Stop, the task queue and the leader monitor return
+	stop chan struct{}
+
+	NodeId string
 }
 
-func NewNode(peerManager *p2p.PeerManager, blockDB *storage.BlockDB, mempool *blockchain.MemPool, consensus *consensus.Consensus, isLeader bool, nodeId string) *Node {
-	return &Node{
-		peerManager: peerManager,
-		blockDB:     blockDB,
-		memPool:     mempool,
-		consensus:   consensus,
+func NewNode(peerManager *p2p.PeerManager, blockDB *storage.BlockDB, mempool *blockchain.MemPool, consensus consensus.Engine, nodeId string, blockProduction *types.BlockProductionConfig) *Node {
+	n := &Node{
+		peerManager:     peerManager,
+		blockDB:         blockDB,
+		memPool:         mempool,
+		consensus:       consensus,
+		blockProduction: defaultBlockProduction,
+		stop:            make(chan struct{}),
 
-		IsLeader: isLeader,
-		NodeId:   nodeId,
+		NodeId: nodeId,
+	}
+	if blockProduction != nil {
+		if blockProduction.BlockInterval > 0 {
+			n.blockProduction.BlockInterval = blockProduction.BlockInterval
+		}
+		n.blockProduction.MaxTransactions = blockProduction.MaxTransactions
+		n.blockProduction.MaxBytes = blockProduction.MaxBytes
+		n.blockProduction.EmptyBlocks = blockProduction.EmptyBlocks
 	}
+
+	return n
 }
 
 func (n *Node) Init() {
 	slog.Info("Init Node success")
 
 	n.recovery()
+	n.restoreMemPool()
 	go n.taskQueue()
+	go n.leaderMonitor()
 }
 
-func (n *Node) recovery() {
-	if n.IsLeader {
-		slog.Debug("This node is leader")
+// Stop ends the block production and the leader monitor, the node no longer proposes nor asks for view changes
+func (n *Node) Stop() {
+	close(n.stop)
+}
+
+// Replay persisted pending transactions, dropping the ones already committed or no longer valid
+func (n *Node) restoreMemPool() {
+	count, err := n.memPool.Restore(n.isValidTransaction)
+	if err != nil {
+		slog.Error("Fail to restore mempool", "err", err)
 		return
 	}
-	slog.Info("Checking sync with leader node")
 
-	leaderLatestBlock, err := n.peerManager.GetLatestBlockFromLeader()
+	slog.Info("Restored pending transactions in mempool", "count", count)
+}
+
+func (n *Node) isValidTransaction(tx *pb.Transaction) bool {
+	return consensus.ValidateTransaction(n.blockDB, tx) == nil
+}
+
+func (n *Node) recovery() {
+	slog.Info("Checking sync with peers")
+
+	peer, leaderLatestBlock, err := n.peerManager.GetHighestPeer()
 	if err != nil {
-		slog.Error("Fail to get lastest block from leader", "err", err)
+		slog.Error("Fail to get lastest block from peers", "err", err)
 		return
 	}
 
@@ -61,16 +110,22 @@ func (n *Node) recovery() {
 		return
 	}
 
-	if leaderLatestBlock.Height == latestBlock.Height {
-		slog.Info("Latest Block with Leader")
+	if leaderLatestBlock.Height <= latestBlock.Height {
+		slog.Info("Latest Block with peers")
+		return
+	}
+
+	slog.Warn("Not Latest Block With peers ! Syncing...", "peer", peer.Address)
+	commonHeight, err := n.peerManager.FindCommonHeight(peer, n.blockDB, latestBlock.Height)
+	if err != nil {
+		slog.Error("Fail to find common block with peer", "peer", peer.Address, "err", err)
 		return
 	}
 
-	slog.Warn("Not Latest Block With Leader ! Syncing...")
-	for height := latestBlock.Height + 1; height <= leaderLatestBlock.Height; height++ {
-		pbLeaderBlock, err := n.peerManager.GetBlockFromLeader(height)
+	for height := commonHeight + 1; height <= leaderLatestBlock.Height; height++ {
+		pbLeaderBlock, err := n.peerManager.GetBlockFromPeer(peer, height)
 		if err != nil {
-			slog.Error("Failed to get block from leader", "height", height, "err", err)
+			slog.Error("Failed to get block from peer", "height", height, "err", err)
 			return
 		}
 		bcLeaderBlock := util.ConvertToBlockchainBlock(pbLeaderBlock)
@@ -94,60 +149,179 @@ func (n *Node) recovery() {
 			return
 		}
 
-		// Save Block
-		if err := n.blockDB.SaveBlock(bcLeaderBlock); err != nil {
+		// Consensus engine checks the proof (quorum certificate, proof of work) and saves the block
+		output, err := n.consensus.HandleSyncBlock(pbLeaderBlock)
+		if err != nil {
 			slog.Error(fmt.Sprintf("Recovery faild - Cant not save block: %v; Error: %v", bcLeaderBlock, err))
 			return
 		}
+		if output != nil {
+			n.memPool.ApplyBlocks(output.CommittedBlocks, output.RevertedBlocks, n.isValidTransaction)
+		}
 	}
 
-	slog.Info("Sync successfully with leader node")
+	slog.Info("Sync successfully with peers")
 }
 
-func (n *Node) createNewBlock() *pb.Block {
-	var bcTransactions []*blockchain.Transaction
+// createNewBlock builds the block on top of the parent, an empty block only when allowEmpty is set
+func (n *Node) createNewBlock(latestBlock *blockchain.Block, parent *blockchain.Block, allowEmpty bool) *pb.Block {
+	// Pending transactions can spend more than the balance together, only the ones valid in order go in.
+	// On a pending parent (pipelining) the engine selects them on top of the pending blocks.
 	pendingTransactions := n.memPool.GetAllPendingTransactions()
-	for _, tx := range pendingTransactions {
-		bcTx := util.ConvertToBlockchainTransaction(tx)
-		bcTransactions = append(bcTransactions, bcTx)
+	if parent.Height == latestBlock.Height {
+		pendingTransactions = consensus.SelectTransactions(n.blockDB, pendingTransactions)
+	}
+	if len(pendingTransactions) == 0 && !allowEmpty {
+		slog.Debug("No valid pending transaction for a new block")
+		return nil
 	}
 
-	latestBlock, err := n.blockDB.GetLatestBlock()
+	pbBlock, err := n.consensus.CreateBlock(pendingTransactions, parent)
+	if errors.Is(err, consensus.ErrProposalInFlight) || errors.Is(err, consensus.ErrRoundProposed) || errors.Is(err, consensus.ErrNoPipelinedTransactions) ||
+		errors.Is(err, consensus.ErrStaleBlock) {
+		slog.Debug("No new block", "height", parent.Height+1, "reason", err)
+		return nil
+	}
 	if err != nil {
-		slog.Error("Cant get latest block", "err", err)
+		slog.Error("Cant create proposal block", "err", err)
 		return nil
 	}
-	block := blockchain.NewBlock(bcTransactions, latestBlock)
-
-	pbBlock := util.ConvertToPbBlock(block)
-	pbBlock.Transactions = pendingTransactions
 
 	return pbBlock
-
 }
 
+// taskQueue produces a block once the block interval elapsed since the latest block, and as soon as the mempool is full enough
 func (n *Node) taskQueue() {
-	if !n.IsLeader {
+	slog.Info("Running Task Queue", "blockInterval", n.blockProduction.BlockInterval, "maxTransactions", n.blockProduction.MaxTransactions,
+		"maxBytes", n.blockProduction.MaxBytes, "emptyBlocks", n.blockProduction.EmptyBlocks)
+
+	ticker := time.NewTicker(blockProductionTick)
+	defer ticker.Stop()
+
+	// Without a size trigger the mempool is not watched: a nil channel never receives
+	var memPoolEvents <-chan blockchain.MemPoolEvent
+	if n.blockProduction.MaxTransactions > 0 || n.blockProduction.MaxBytes > 0 {
+		events, unsubscribe := n.memPool.Subscribe()
+		defer unsubscribe()
+		memPoolEvents = events
+	}
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			if n.isBlockDue() {
+				n.produceBlock(n.blockProduction.EmptyBlocks)
+			}
+		case event := <-memPoolEvents:
+			if event.Type != blockchain.MEMPOOL_ADDED || !n.isMemPoolFull() {
+				continue
+			}
+			slog.Debug("Task Queue Create Block: Mempool full")
+			n.produceBlock(false)
+		}
+	}
+}
+
+// isBlockDue tells if the block interval elapsed since this node saw the latest block, whoever produced it
+func (n *Node) isBlockDue() bool {
+	latestHeight, err := n.blockDB.GetlatestHeight()
+	if err != nil {
+		slog.Error("Task Queue Create Block: Cant get latest height", "err", err)
+		return false
+	}
+
+	if uint64(latestHeight) != n.latestHeight || n.latestHeightAt.IsZero() {
+		n.latestHeight = uint64(latestHeight)
+		n.latestHeightAt = time.Now()
+	}
+
+	return time.Since(n.latestHeightAt) >= time.Duration(n.blockProduction.BlockInterval)*time.Second
+}
+
+// isMemPoolFull tells if the pending transactions reach the count or size that triggers a block
+func (n *Node) isMemPoolFull() bool {
+	pendingTransactions := n.memPool.GetAllPendingTransactions()
+	if n.blockProduction.MaxTransactions > 0 && len(pendingTransactions) >= n.blockProduction.MaxTransactions {
+		return true
+	}
+
+	if n.blockProduction.MaxBytes > 0 {
+		size := 0
+		for _, tx := range pendingTransactions {
+			size += proto.Size(tx)
+		}
+		return size >= n.blockProduction.MaxBytes
+	}
+
+	return false
+}
+
+// produceBlock creates and proposes the block of the next height when this node is its proposer
+func (n *Node) produceBlock(allowEmpty bool) {
+	// Only the proposer of the next height creates the block
+	latestBlock, err := n.blockDB.GetLatestBlock()
+	if err != nil {
+		slog.Error("Task Queue Create Block: Cant get latest block", "err", err)
+		return
+	}
+	// Pipelining: the next height is above the pending blocks
+	parent := n.consensus.ProposalParent(latestBlock)
+	// PoW: no proposer, every node mines
+	if proposer := n.consensus.GetProposer(parent.Height + 1); proposer != "" && proposer != n.NodeId {
+		slog.Debug("Task Queue Create Block: Not proposer of next height", "height", parent.Height+1, "proposer", proposer)
 		return
 	}
-	slog.Info("Running Task Queue")
 
-	ticker := time.NewTicker(5 * time.Second)
+	if len(n.memPool.GetAllPendingTransactions()) == 0 && !allowEmpty {
+		slog.Debug("Task Queue Create Block: No Transaction Found")
+		return
+	}
+
+	slog.Debug("Task Queue Create Block: Creating new block", "height", parent.Height+1)
+	block := n.createNewBlock(latestBlock, parent, allowEmpty)
+	if block == nil {
+		return
+	}
+
+	n.proposeBlock(block)
+}
+
+// proposeBlock hands the block to the consensus engine, then sends it and the messages of this node
+func (n *Node) proposeBlock(block *pb.Block) {
+	output, err := n.consensus.Propose(block)
+	if err != nil {
+		slog.Error("Cant propose block", "err", err)
+		return
+	}
+
+	n.peerManager.BroastCastProposeBlock(block)
+	n.peerManager.SendConsensusOutput(output)
+
+	if output != nil {
+		n.memPool.ApplyBlocks(output.CommittedBlocks, output.RevertedBlocks, n.isValidTransaction)
+	}
+}
+
+// leaderMonitor ticks the consensus engine: heartbeats while this node is the proposer of the next height,
+// round timeouts and view change
+func (n *Node) leaderMonitor() {
+	ticker := time.NewTicker(consensus.HeartbeatInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case t := <-ticker.C:
-			pendingTransactions := n.memPool.GetAllPendingTransactions()
-			if len(pendingTransactions) > 0 {
-				slog.Info("Task Queue Create Block: Creating new block", "t", t)
-				block := n.createNewBlock()
-
-				n.consensus.SetProposalBlock(block)
-				n.peerManager.BroastCastProposeBlock(block)
-			} else {
-				slog.Debug("Task Queue Create Block: No Transaction Found", "t", t)
-			}
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+
+		output, err := n.consensus.Tick()
+		if err != nil {
+			slog.Error("Leader Monitor: Cant tick consensus engine", "err", err)
+			continue
 		}
+		n.peerManager.SendConsensusOutput(output)
 	}
 }
@@ -0,0 +1,174 @@
+package node
+
+import (
+	"fmt"
+	"go-blockchain-ber1/pkg/consensus"
+	"go-blockchain-ber1/pkg/types"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	// Every validator of a cluster logs each step
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+func TestClusterCommitsTransactions(t *testing.T) {
+	for _, engineName := range []string{"vote", "pbft"} {
+		t.Run(engineName, func(t *testing.T) {
+			cluster := newTestCluster(t, 4, engineName, &types.BlockProductionConfig{BlockInterval: 1, MaxTransactions: 2})
+
+			alice := newTestAccount(t)
+			for range 6 {
+				cluster.submit(alice.transfer("bob", 1))
+			}
+
+			cluster.waitUntil(10*time.Second, "holding the transactions of alice", func(node *testNode) bool {
+				nonce, err := node.blockDB.GetAccountNonce(alice.address)
+				return err == nil && nonce == 6 && len(node.node.memPool.GetAllPendingTransactions()) == 0
+			})
+		})
+	}
+}
+
+// Transactions of a benchmark block, a full gossip batch is sent to the peers at once
+const benchmarkBlockSize = 100
+
+// BenchmarkClusterPipelineDepth commits blocks of transactions on 4 validators. The client keeps as many blocks
+// in flight as the pipeline depth: with 1 it waits for each block to be committed before sending the next.
+func BenchmarkClusterPipelineDepth(b *testing.B) {
+	for _, pipelineDepth := range []uint64{1, 4} {
+		b.Run(fmt.Sprintf("depth=%d", pipelineDepth), func(b *testing.B) {
+			cluster := newTestCluster(b, 4, "vote", &types.BlockProductionConfig{BlockInterval: 1, MaxTransactions: benchmarkBlockSize, PipelineDepth: pipelineDepth})
+
+			var accounts []*testAccount
+			for b.Loop() {
+				account := newTestAccount(b)
+				for range benchmarkBlockSize {
+					cluster.submit(account.transfer("bob", 1))
+				}
+				accounts = append(accounts, account)
+
+				if len(accounts) < int(pipelineDepth) {
+					continue
+				}
+				committed := accounts[len(accounts)-int(pipelineDepth)]
+				cluster.waitUntil(30*time.Second, "holding the block of transactions", func(node *testNode) bool {
+					nonce, err := node.blockDB.GetAccountNonce(committed.address)
+					return err == nil && nonce == committed.nonce
+				})
+			}
+			b.ReportMetric(float64(b.N*benchmarkBlockSize)/b.Elapsed().Seconds(), "tx/s")
+		})
+	}
+}
+
+func TestClusterKeepsProducingWhenLeaderStops(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the leader timeout of the stopped leader")
+	}
+
+	cluster := newTestCluster(t, 4, "vote", &types.BlockProductionConfig{BlockInterval: 1, MaxTransactions: 1})
+	alice := newTestAccount(t)
+	commit := func(timeout time.Duration) {
+		tx := alice.transfer("bob", 1)
+		cluster.submit(tx)
+		cluster.waitUntil(timeout, fmt.Sprintf("holding transaction %d of alice", tx.Nonce), func(node *testNode) bool {
+			nonce, err := node.blockDB.GetAccountNonce(alice.address)
+			return err == nil && nonce == tx.Nonce
+		})
+	}
+
+	commit(10 * time.Second)
+	leader := cluster.proposer()
+	height := cluster.nodes[leader].latestHeight()
+	cluster.stop(leader)
+
+	// The height of the stopped leader goes to the next round by view change, the next heights follow
+	commit(consensus.ProposeTimeout + 20*time.Second)
+	commit(10 * time.Second)
+	commit(10 * time.Second)
+
+	for _, node := range cluster.running() {
+		if latestHeight := node.latestHeight(); latestHeight < height+3 {
+			t.Fatalf("%s at height %d, want %d", node.node.NodeId, latestHeight, height+3)
+		}
+		block, err := node.blockDB.GetBlock(height + 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.ProposerId == leader || block.Round == 0 {
+			t.Fatalf("block %d proposed by %s at round %d, want another validator after a view change", height+1, block.ProposerId, block.Round)
+		}
+	}
+}
+
+// assertHeightStays checks the validator commits no block for the duration
+func assertHeightStays(t *testing.T, node *testNode, height uint64, duration time.Duration) {
+	t.Helper()
+
+	time.Sleep(duration)
+	if latestHeight := node.latestHeight(); latestHeight != height {
+		t.Fatalf("%s at height %d after %s, want %d", node.node.NodeId, latestHeight, duration, height)
+	}
+}
+
+func TestBlockIntervalTrigger(t *testing.T) {
+	cluster := newTestCluster(t, 4, "vote", &types.BlockProductionConfig{BlockInterval: 2})
+	node := cluster.nodes[cluster.proposer()]
+
+	cluster.submit(newTestAccount(t).transfer("bob", 1))
+	assertHeightStays(t, node, 1, time.Second)
+	cluster.waitHeight(2, 5*time.Second)
+
+	// Nothing pending, no empty block
+	assertHeightStays(t, node, 2, 3*time.Second)
+}
+
+func TestTransactionCountTrigger(t *testing.T) {
+	cluster := newTestCluster(t, 4, "vote", &types.BlockProductionConfig{BlockInterval: 60, MaxTransactions: 3})
+	node := cluster.nodes[cluster.proposer()]
+
+	alice := newTestAccount(t)
+	cluster.submit(alice.transfer("bob", 1))
+	cluster.submit(alice.transfer("bob", 1))
+	assertHeightStays(t, node, 1, time.Second)
+
+	cluster.submit(alice.transfer("bob", 1))
+	cluster.waitHeight(2, 3*time.Second)
+	if block, err := node.blockDB.GetBlock(2); err != nil || len(block.Transactions) != 3 {
+		t.Fatalf("block 2 is not the 3 transactions: %v", err)
+	}
+}
+
+func TestTransactionSizeTrigger(t *testing.T) {
+	alice := newTestAccount(t)
+	first := alice.transfer("bob", 1)
+	cluster := newTestCluster(t, 4, "vote", &types.BlockProductionConfig{BlockInterval: 60, MaxBytes: proto.Size(first) + 1})
+	node := cluster.nodes[cluster.proposer()]
+
+	cluster.submit(first)
+	assertHeightStays(t, node, 1, time.Second)
+
+	cluster.submit(alice.transfer("bob", 1))
+	cluster.waitHeight(2, 3*time.Second)
+}
+
+func TestEmptyBlocksTrigger(t *testing.T) {
+	cluster := newTestCluster(t, 4, "vote", &types.BlockProductionConfig{BlockInterval: 1, EmptyBlocks: true})
+
+	// One empty block per interval with an empty mempool
+	cluster.waitHeight(3, 5*time.Second)
+	block, err := cluster.nodes["node1"].blockDB.GetBlock(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Transactions) != 0 {
+		t.Fatalf("block 2 holds %d transactions, want an empty block", len(block.Transactions))
+	}
+}
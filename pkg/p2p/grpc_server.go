@@ -3,16 +3,17 @@ package p2p
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"go-blockchain-ber1/pkg/blockchain"
 	"go-blockchain-ber1/pkg/consensus"
 	"go-blockchain-ber1/pkg/p2p/pb"
 	"go-blockchain-ber1/pkg/storage"
 	"go-blockchain-ber1/pkg/util"
-	"go-blockchain-ber1/pkg/wallet"
 	"log"
 	"log/slog"
 	"net"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
@@ -21,74 +22,127 @@ import (
 type NodeStatus string
 
 var (
-	IDLE                          NodeStatus = "IDLE"
-	SYNCING                       NodeStatus = "SYNCING"
-	VALIDATING_BLOCK              NodeStatus = "VALIDATING_BLOCK"
-	VERIFYING_TRANSACTION         NodeStatus = "VERIFYING_TRANSACTION"
-	FORWARD_TRANSACTION_TO_LEADER NodeStatus = "FORWARD_TRANSACTION_TO_LEADER"
-	WAITING_NEXT_BLOCK            NodeStatus = "WAITING_NEXT_BLOCK"
-	SENT_VOTE_TO_LEADER           NodeStatus = "SENT_VOTE_TO_LEADER"
-	PROCESSING_VOTE               NodeStatus = "PROCESSING_VOTE"
-	COMMIT_BLOCK                  NodeStatus = "COMMIT_BLOCK"
+	IDLE                         NodeStatus = "IDLE"
+	SYNCING                      NodeStatus = "SYNCING"
+	VALIDATING_BLOCK             NodeStatus = "VALIDATING_BLOCK"
+	VERIFYING_TRANSACTION        NodeStatus = "VERIFYING_TRANSACTION"
+	GOSSIP_TRANSACTION           NodeStatus = "GOSSIP_TRANSACTION"
+	WAITING_NEXT_BLOCK           NodeStatus = "WAITING_NEXT_BLOCK"
+	SENT_CONSENSUS_MESSAGE       NodeStatus = "SENT_CONSENSUS_MESSAGE"
+	PROCESSING_CONSENSUS_MESSAGE NodeStatus = "PROCESSING_CONSENSUS_MESSAGE"
+	COMMIT_BLOCK                 NodeStatus = "COMMIT_BLOCK"
 )
 
 type grpcServer struct {
 	pb.UnimplementedBlockchainServer
 	blockDB     *storage.BlockDB
 	memPool     *blockchain.MemPool
-	consensus   *consensus.Consensus
+	consensus   consensus.Engine
 	peerManager *PeerManager
 
-	isLeader   bool
 	nodeId     string
-	nodeStatus NodeStatus
-}
+	statusMu   sync.Mutex
+	nodeStatus NodeStatus // Set by the handlers, read by the SteamNodeInfo streams
 
-func (s *grpcServer) SendTransaction(ctx context.Context, tx *pb.Transaction) (*pb.Empty, error) {
-	bcTx := util.ConvertToBlockchainTransaction(tx)
+	server *grpc.Server
+}
 
-	if !s.isLeader {
-		s.nodeStatus = FORWARD_TRANSACTION_TO_LEADER
+func (s *grpcServer) setStatus(status NodeStatus) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.nodeStatus = status
+}
 
-		slog.Warn("This node is node leader for handle send transaction service ! Forward to leader")
-		if err := s.peerManager.SendTransactionToLeader(ctx, tx); err != nil {
-			return nil, err
-		}
+func (s *grpcServer) getStatus() NodeStatus {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.nodeStatus
+}
 
-		return nil, nil
-	}
+func (s *grpcServer) SendTransaction(ctx context.Context, tx *pb.Transaction) (*pb.Empty, error) {
 	slog.Info("Trigger Sent Transaction")
 
 	// Verify Transaction
-	s.nodeStatus = VERIFYING_TRANSACTION
+	s.setStatus(VERIFYING_TRANSACTION)
 
-	publicKey, err := util.DecodePublicKey(string(tx.PublicKey))
-	if err != nil {
+	if err := consensus.ValidateTransaction(s.blockDB, tx); err != nil {
 		return nil, err
 	}
 
-	if !wallet.VerifyTransaction(bcTx, publicKey) {
-		return nil, fmt.Errorf("transaction cant verify")
+	// store transaction in pending
+	isAdded, err := s.memPool.AddPendingTransaction(tx)
+	if err != nil {
+		return nil, err
 	}
 
-	// store transaction in pending
-	s.memPool.AddPendingTransaction(tx)
+	// Every validator keeps the transaction so another node can include it if the leader dies
+	if isAdded {
+		s.setStatus(GOSSIP_TRANSACTION)
+		s.peerManager.GossipTransaction(tx)
+	}
 
-	s.nodeStatus = WAITING_NEXT_BLOCK
+	s.setStatus(WAITING_NEXT_BLOCK)
 
 	slog.Info("Transaction added in mempool")
 
 	return nil, nil
 }
 
+func (s *grpcServer) GossipTransactions(ctx context.Context, batch *pb.TransactionBatch) (*pb.Empty, error) {
+	slog.Debug("Trigger Gossip Transactions", "count", len(batch.Transactions))
+
+	for _, tx := range batch.Transactions {
+		if err := consensus.ValidateTransaction(s.blockDB, tx); err != nil {
+			slog.Warn("Drop gossiped transaction", "err", err)
+			continue
+		}
+
+		isAdded, err := s.memPool.AddPendingTransaction(tx)
+		if errors.Is(err, blockchain.ErrReplacementUnderpriced) {
+			slog.Warn("Drop gossiped transaction", "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Pass new transactions on, duplicates stop here
+		if isAdded {
+			s.peerManager.GossipTransaction(tx)
+		}
+	}
+
+	return nil, nil
+}
+
 func (s *grpcServer) GetBlock(ctx context.Context, blockHeight *pb.BlockHeight) (*pb.Block, error) {
 	block, err := s.blockDB.GetBlock(blockHeight.Height)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.withCertificate(block)
+}
+
+func (s *grpcServer) GetBlockByHash(ctx context.Context, blockHash *pb.BlockHash) (*pb.Block, error) {
+	block, err := s.blockDB.GetBlockByHash(blockHash.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withCertificate(block)
+}
+
+// withCertificate returns the block with its quorum certificate, peers syncing it check the certificate
+func (s *grpcServer) withCertificate(block *blockchain.Block) (*pb.Block, error) {
 	pbBlock := util.ConvertToPbBlock(block)
 
+	certificate, err := s.blockDB.GetCertificate(block.Height)
+	if err != nil {
+		return nil, err
+	}
+	pbBlock.Certificate = certificate
+
 	return pbBlock, nil
 }
 
@@ -112,71 +166,171 @@ func (s *grpcServer) ProposeBlock(ctx context.Context, block *pb.Block) (*pb.Emp
 	}
 
 	//
-	s.nodeStatus = VALIDATING_BLOCK
+	s.setStatus(VALIDATING_BLOCK)
 
-	isAprrove, err := s.consensus.HandleProposeBlock(block, latestBlock)
+	output, err := s.consensus.HandleProposeBlock(block, latestBlock)
 	if err != nil {
 		return nil, err
 	}
 
-	s.nodeStatus = SENT_VOTE_TO_LEADER
-
-	s.peerManager.SendVoteToLeader(ctx, &pb.AVote{
-		Approve:     isAprrove,
-		NodeId:      s.nodeId,
-		BlockHeight: block.Height,
-	})
+	if output != nil && len(output.Messages) > 0 {
+		s.setStatus(SENT_CONSENSUS_MESSAGE)
+	}
 
-	return nil, nil
+	return nil, s.handleOutput(output)
 }
 
-func (s *grpcServer) Vote(ctx context.Context, vote *pb.AVote) (*pb.Empty, error) {
-	if !s.isLeader {
-		slog.Warn("This node is not leader for trigger grpc server vote service")
-		return nil, nil
-	}
-	slog.Info("Trigger Leader Recevice Vote")
+// SendConsensusMessage hands a vote, PBFT phase, heartbeat or view change of a peer to the consensus engine
+func (s *grpcServer) SendConsensusMessage(ctx context.Context, msg *pb.ConsensusMessage) (*pb.Empty, error) {
+	slog.Debug("Trigger Consensus Message", "type", msg.Type)
 
-	s.nodeStatus = PROCESSING_VOTE
+	s.setStatus(PROCESSING_CONSENSUS_MESSAGE)
 
-	if s.consensus.GetProposalBlock() == nil {
-		s.nodeStatus = IDLE
+	output, err := s.consensus.HandleMessage(msg)
+	if err != nil {
+		slog.Warn("Reject consensus message", "type", msg.Type, "err", err)
+		return nil, err
+	}
+
+	if output == nil {
+		s.setStatus(IDLE)
 		return nil, nil
 	}
 
-	if s.consensus.HandleVote(vote) {
-		// Commit block
-		s.CommitBlock(context.Background(), nil)
+	return nil, s.handleOutput(output)
+}
+
+func (s *grpcServer) CommitBlock(ctx context.Context, cert *pb.QuorumCertificate) (*pb.Empty, error) {
+	slog.Info("Trigger Commit Block")
 
-		s.peerManager.BroastCastCommitBlock()
+	s.setStatus(COMMIT_BLOCK)
 
-		// Clear mem pool
-		s.memPool.ClearAllPendingTransaction()
+	output, err := s.consensus.HandleCommitBlock(cert)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	return nil, s.handleOutput(output)
 }
 
-func (s *grpcServer) CommitBlock(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
-	slog.Info("Trigger Commit Block")
+// handleOutput sends the messages of a consensus step, then applies its committed block or syncs
+func (s *grpcServer) handleOutput(output *consensus.Output) error {
+	if output == nil {
+		return nil
+	}
 
-	if !s.isLeader && s.consensus.GetProposalBlock() == nil {
-		s.nodeStatus = SYNCING
+	// Only the transactions included in the blocks leave the mempool
+	s.memPool.ApplyBlocks(output.CommittedBlocks, output.RevertedBlocks, s.isValidTransaction)
 
-		if err := s.syncWithLeader(); err != nil {
-			return nil, err
+	s.peerManager.SendConsensusOutput(output)
+
+	if output.NeedsSync {
+		s.setStatus(SYNCING)
+
+		if err := s.syncWithPeers(); err != nil {
+			return err
 		}
-	} else {
-		s.nodeStatus = COMMIT_BLOCK
+	}
 
-		if err := s.consensus.HandleCommitBlock(); err != nil {
-			return nil, err
+	if len(output.CommittedBlocks) > 0 || output.NeedsSync {
+		s.setStatus(IDLE)
+
+		slog.Info("Flow DONE")
+	}
+
+	return nil
+}
+
+// Page of GetMempool, a larger limit is clamped
+const (
+	defaultMempoolPageLimit = 50
+	maxMempoolPageLimit     = 1000
+)
+
+func (s *grpcServer) GetMempool(ctx context.Context, req *pb.GetMempoolRequest) (*pb.GetMempoolResponse, error) {
+	var entries []*pb.MempoolEntry
+	for _, tx := range s.memPool.GetAllPendingTransactions() {
+		if len(req.Sender) > 0 && !bytes.Equal(tx.Sender, req.Sender) {
+			continue
+		}
+		entries = append(entries, toMempoolEntry(tx))
+	}
+
+	// Bounds in int: offset + limit of the request can overflow uint32
+	total := len(entries)
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultMempoolPageLimit
+	}
+	limit = min(limit, maxMempoolPageLimit)
+	start := min(int(req.Offset), total)
+	end := min(start+limit, total)
+
+	return &pb.GetMempoolResponse{
+		Entries: entries[start:end],
+		Total:   uint32(total),
+	}, nil
+}
+
+func (s *grpcServer) SubscribeMempool(_ *pb.Empty, stream pb.Blockchain_SubscribeMempoolServer) error {
+	slog.Info("Trigger Subscribe Mempool: On")
+
+	events, unsubscribe := s.memPool.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			slog.Info("Trigger Subscribe Mempool: Off")
+			return nil
+		case event := <-events:
+			response := pb.MempoolEvent{
+				Type:  string(event.Type),
+				Entry: toMempoolEntry(event.Transaction),
+			}
+			if err := stream.Send(&response); err != nil {
+				return err
+			}
 		}
 	}
+}
+
+func (s *grpcServer) GetAccountNonce(ctx context.Context, account *pb.Account) (*pb.AccountNonce, error) {
+	committedNonce, err := s.blockDB.GetAccountNonce(account.Address)
+	if err != nil {
+		return nil, err
+	}
 
-	s.nodeStatus = IDLE
+	pendingNonce, isPending := s.memPool.GetPendingNonce(account.Address)
+	if !isPending || pendingNonce < committedNonce {
+		pendingNonce = committedNonce
+	}
 
-	slog.Info("Flow DONE")
+	return &pb.AccountNonce{
+		CommittedNonce: committedNonce,
+		PendingNonce:   pendingNonce,
+	}, nil
+}
+
+func (s *grpcServer) isValidTransaction(tx *pb.Transaction) bool {
+	return consensus.ValidateTransaction(s.blockDB, tx) == nil
+}
+
+func toMempoolEntry(tx *pb.Transaction) *pb.MempoolEntry {
+	return &pb.MempoolEntry{
+		Hash:        util.ConvertToBlockchainTransaction(tx).Hash(),
+		Transaction: tx,
+	}
+}
+
+func (s *grpcServer) SendEvidence(ctx context.Context, evidence *pb.Evidence) (*pb.Empty, error) {
+	slog.Debug("Trigger Evidence", "type", evidence.Type, "nodeId", evidence.NodeId, "height", evidence.Height)
+
+	if err := s.consensus.HandleEvidence(evidence); err != nil {
+		slog.Warn("Reject evidence", "err", err)
+		return nil, err
+	}
 
 	return nil, nil
 }
@@ -194,7 +348,7 @@ func (s *grpcServer) StreamNodeInfo(_ *pb.Empty, stream pb.Blockchain_StreamNode
 			slog.Info("Trigger Steam Node Info: Off")
 			return nil
 		case <-timer.C:
-			newStatus := string(s.nodeStatus)
+			newStatus := string(s.getStatus())
 			if oldStatus != newStatus {
 
 				slog.Debug("Change : ", "oldStatus", oldStatus, "newStatus", newStatus)
@@ -213,40 +367,50 @@ func (s *grpcServer) StreamNodeInfo(_ *pb.Empty, stream pb.Blockchain_StreamNode
 	}
 }
 
-func NewGRPCServer(db *storage.BlockDB, pm *PeerManager, memPool *blockchain.MemPool, consensus *consensus.Consensus, isLeader bool, nodeId string) *grpcServer {
-	return &grpcServer{
+func NewGRPCServer(db *storage.BlockDB, pm *PeerManager, memPool *blockchain.MemPool, consensus consensus.Engine, nodeId string) *grpcServer {
+	g := &grpcServer{
 		blockDB:     db,
 		peerManager: pm,
 		memPool:     memPool,
 		consensus:   consensus,
-		isLeader:    isLeader,
 		nodeId:      nodeId,
 		nodeStatus:  IDLE,
+		server:      grpc.NewServer(),
 	}
+	pb.RegisterBlockchainServer(g.server, g)
+
+	return g
 }
 
 func (g *grpcServer) Init(addressPort string) {
 	slog.Info("GRPC server Init")
 
-	serviceRegister := grpc.NewServer()
-	pb.RegisterBlockchainServer(serviceRegister, g)
-
 	listener, err := net.Listen("tcp", addressPort)
 	if err != nil {
 		log.Fatalf("tcp listener failed: %v", err)
 	}
 
-	if err := serviceRegister.Serve(listener); err != nil {
+	if err := g.Serve(listener); err != nil {
 		log.Fatalf("failed to serve gRPC: %v", err)
 	}
 }
 
-func (s *grpcServer) syncWithLeader() error {
-	slog.Info("Syncing block with leader")
+// Serve answers the peers on the listener until Stop
+func (g *grpcServer) Serve(listener net.Listener) error {
+	return g.server.Serve(listener)
+}
+
+// Stop closes the listener and the connections of the peers at once
+func (g *grpcServer) Stop() {
+	g.server.Stop()
+}
+
+func (s *grpcServer) syncWithPeers() error {
+	slog.Info("Syncing block with peers")
 
-	leaderLatestBlock, err := s.peerManager.GetLatestBlockFromLeader()
+	peer, leaderLatestBlock, err := s.peerManager.GetHighestPeer()
 	if err != nil {
-		slog.Error("Fail to get lastest block from leader", "err", err)
+		slog.Error("Fail to get lastest block from peers", "err", err)
 		return err
 	}
 
@@ -256,14 +420,20 @@ func (s *grpcServer) syncWithLeader() error {
 		return err
 	}
 
-	if leaderLatestBlock.Height == latestBlock.Height {
+	if leaderLatestBlock.Height <= latestBlock.Height {
 		return nil
 	}
 
-	for height := latestBlock.Height + 1; height <= leaderLatestBlock.Height; height++ {
-		pbLeaderBlock, err := s.peerManager.GetBlockFromLeader(height)
+	commonHeight, err := s.peerManager.FindCommonHeight(peer, s.blockDB, latestBlock.Height)
+	if err != nil {
+		slog.Error("Fail to find common block with peer", "peer", peer.Address, "err", err)
+		return err
+	}
+
+	for height := commonHeight + 1; height <= leaderLatestBlock.Height; height++ {
+		pbLeaderBlock, err := s.peerManager.GetBlockFromPeer(peer, height)
 		if err != nil {
-			slog.Error("Failed to get block from leader", "height", height, "err", err)
+			slog.Error("Failed to get block from peer", "height", height, "peer", peer.Address, "err", err)
 			return err
 		}
 		bcLeaderBlock := util.ConvertToBlockchainBlock(pbLeaderBlock)
@@ -287,11 +457,15 @@ func (s *grpcServer) syncWithLeader() error {
 			return err
 		}
 
-		// Save Block
-		if err := s.blockDB.SaveBlock(bcLeaderBlock); err != nil {
+		// Consensus engine checks the proof (quorum certificate, proof of work) and saves the block
+		output, err := s.consensus.HandleSyncBlock(pbLeaderBlock)
+		if err != nil {
 			slog.Error(fmt.Sprintf("Recovery faild - Cant not save block: %v; Error: %v", bcLeaderBlock, err))
 			return err
 		}
+		if output != nil {
+			s.memPool.ApplyBlocks(output.CommittedBlocks, output.RevertedBlocks, s.isValidTransaction)
+		}
 	}
 
 	slog.Info("Sync successfully In Commit Block")
@@ -0,0 +1,40 @@
+package p2p
+
+import (
+	"context"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"math"
+	"testing"
+)
+
+func TestGetMempoolPaging(t *testing.T) {
+	memPool := blockchain.NewMemPool(storage.NewMemPoolDB(storage.NewMemoryStore()))
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		memPool.AddPendingTransaction(&pb.Transaction{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 1, Nonce: nonce})
+	}
+	server := &grpcServer{memPool: memPool}
+
+	pages := []struct {
+		offset, limit uint32
+		entries       int
+	}{
+		{0, 0, 3},
+		{1, 1, 1},
+		{2, 10, 1},
+		{5, 10, 0},
+		// offset + limit overflows uint32
+		{1, math.MaxUint32, 2},
+		{math.MaxUint32, math.MaxUint32, 0},
+	}
+	for _, page := range pages {
+		response, err := server.GetMempool(context.Background(), &pb.GetMempoolRequest{Offset: page.offset, Limit: page.limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Entries) != page.entries || response.Total != 3 {
+			t.Fatalf("offset %d limit %d: %d entries of %d, want %d of 3", page.offset, page.limit, len(response.Entries), response.Total, page.entries)
+		}
+	}
+}
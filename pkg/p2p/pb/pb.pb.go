@@ -58,15 +58,20 @@ func (*Empty) Descriptor() ([]byte, []int) {
 }
 
 type Transaction struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Sender        []byte                 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
-	Receiver      []byte                 `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
-	Amount        float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Signature     []byte                 `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
-	PublicKey     []byte                 `protobuf:"bytes,6,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Sender           []byte                 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	Receiver         []byte                 `protobuf:"bytes,2,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Amount           float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Timestamp        int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Signature        []byte                 `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	PublicKey        []byte                 `protobuf:"bytes,6,opt,name=publicKey,proto3" json:"publicKey,omitempty"`
+	Fee              float64                `protobuf:"fixed64,7,opt,name=fee,proto3" json:"fee,omitempty"`
+	Nonce            uint64                 `protobuf:"varint,8,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Type             string                 `protobuf:"bytes,9,opt,name=type,proto3" json:"type,omitempty"`
+	ValidatorId      string                 `protobuf:"bytes,10,opt,name=validator_id,json=validatorId,proto3" json:"validator_id,omitempty"`
+	ValidatorAddress string                 `protobuf:"bytes,11,opt,name=validator_address,json=validatorAddress,proto3" json:"validator_address,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *Transaction) Reset() {
@@ -141,6 +146,85 @@ func (x *Transaction) GetPublicKey() []byte {
 	return nil
 }
 
+func (x *Transaction) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Transaction) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Transaction) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Transaction) GetValidatorId() string {
+	if x != nil {
+		return x.ValidatorId
+	}
+	return ""
+}
+
+func (x *Transaction) GetValidatorAddress() string {
+	if x != nil {
+		return x.ValidatorAddress
+	}
+	return ""
+}
+
+type TransactionBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionBatch) Reset() {
+	*x = TransactionBatch{}
+	mi := &file___proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionBatch) ProtoMessage() {}
+
+func (x *TransactionBatch) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionBatch.ProtoReflect.Descriptor instead.
+func (*TransactionBatch) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TransactionBatch) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
 type Block struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	Transactions      []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
@@ -148,13 +232,21 @@ type Block struct {
 	PreviousBlockHash []byte                 `protobuf:"bytes,3,opt,name=previous_block_hash,json=previousBlockHash,proto3" json:"previous_block_hash,omitempty"`
 	CurrentBlockHash  []byte                 `protobuf:"bytes,4,opt,name=current_block_hash,json=currentBlockHash,proto3" json:"current_block_hash,omitempty"`
 	Height            uint64                 `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"`
+	ProposerId        string                 `protobuf:"bytes,6,opt,name=proposer_id,json=proposerId,proto3" json:"proposer_id,omitempty"`
+	ProposerSignature []byte                 `protobuf:"bytes,7,opt,name=proposer_signature,json=proposerSignature,proto3" json:"proposer_signature,omitempty"`
+	Round             uint32                 `protobuf:"varint,8,opt,name=round,proto3" json:"round,omitempty"`
+	Certificate       *QuorumCertificate     `protobuf:"bytes,9,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	Timestamp         int64                  `protobuf:"varint,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Nonce             uint64                 `protobuf:"varint,11,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Difficulty        uint32                 `protobuf:"varint,12,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	Evidence          []*Evidence            `protobuf:"bytes,13,rep,name=evidence,proto3" json:"evidence,omitempty"`
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *Block) Reset() {
 	*x = Block{}
-	mi := &file___proto_msgTypes[2]
+	mi := &file___proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -166,7 +258,7 @@ func (x *Block) String() string {
 func (*Block) ProtoMessage() {}
 
 func (x *Block) ProtoReflect() protoreflect.Message {
-	mi := &file___proto_msgTypes[2]
+	mi := &file___proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -179,7 +271,7 @@ func (x *Block) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Block.ProtoReflect.Descriptor instead.
 func (*Block) Descriptor() ([]byte, []int) {
-	return file___proto_rawDescGZIP(), []int{2}
+	return file___proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Block) GetTransactions() []*Transaction {
@@ -217,18 +309,77 @@ func (x *Block) GetHeight() uint64 {
 	return 0
 }
 
+func (x *Block) GetProposerId() string {
+	if x != nil {
+		return x.ProposerId
+	}
+	return ""
+}
+
+func (x *Block) GetProposerSignature() []byte {
+	if x != nil {
+		return x.ProposerSignature
+	}
+	return nil
+}
+
+func (x *Block) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *Block) GetCertificate() *QuorumCertificate {
+	if x != nil {
+		return x.Certificate
+	}
+	return nil
+}
+
+func (x *Block) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Block) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Block) GetDifficulty() uint32 {
+	if x != nil {
+		return x.Difficulty
+	}
+	return 0
+}
+
+func (x *Block) GetEvidence() []*Evidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
 type AVote struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Approve       bool                   `protobuf:"varint,1,opt,name=approve,proto3" json:"approve,omitempty"`
 	NodeId        string                 `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
 	BlockHeight   uint64                 `protobuf:"varint,3,opt,name=blockHeight,proto3" json:"blockHeight,omitempty"`
+	BlockHash     []byte                 `protobuf:"bytes,4,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	Round         uint32                 `protobuf:"varint,6,opt,name=round,proto3" json:"round,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AVote) Reset() {
 	*x = AVote{}
-	mi := &file___proto_msgTypes[3]
+	mi := &file___proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -240,7 +391,7 @@ func (x *AVote) String() string {
 func (*AVote) ProtoMessage() {}
 
 func (x *AVote) ProtoReflect() protoreflect.Message {
-	mi := &file___proto_msgTypes[3]
+	mi := &file___proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -253,7 +404,7 @@ func (x *AVote) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AVote.ProtoReflect.Descriptor instead.
 func (*AVote) Descriptor() ([]byte, []int) {
-	return file___proto_rawDescGZIP(), []int{3}
+	return file___proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AVote) GetApprove() bool {
@@ -277,28 +428,52 @@ func (x *AVote) GetBlockHeight() uint64 {
 	return 0
 }
 
-type BlockHeight struct {
+func (x *AVote) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *AVote) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *AVote) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+type Heartbeat struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Height        uint64                 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Height        uint64                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round         uint32                 `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *BlockHeight) Reset() {
-	*x = BlockHeight{}
-	mi := &file___proto_msgTypes[4]
+func (x *Heartbeat) Reset() {
+	*x = Heartbeat{}
+	mi := &file___proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *BlockHeight) String() string {
+func (x *Heartbeat) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BlockHeight) ProtoMessage() {}
+func (*Heartbeat) ProtoMessage() {}
 
-func (x *BlockHeight) ProtoReflect() protoreflect.Message {
-	mi := &file___proto_msgTypes[4]
+func (x *Heartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -309,41 +484,64 @@ func (x *BlockHeight) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BlockHeight.ProtoReflect.Descriptor instead.
-func (*BlockHeight) Descriptor() ([]byte, []int) {
-	return file___proto_rawDescGZIP(), []int{4}
+// Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{5}
 }
 
-func (x *BlockHeight) GetHeight() uint64 {
+func (x *Heartbeat) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Heartbeat) GetHeight() uint64 {
 	if x != nil {
 		return x.Height
 	}
 	return 0
 }
 
-type SteamNodeInfoResponse struct {
+func (x *Heartbeat) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *Heartbeat) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ViewChange struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
-	NodeStatus    string                 `protobuf:"bytes,2,opt,name=nodeStatus,proto3" json:"nodeStatus,omitempty"`
+	Height        uint64                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round         uint32                 `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SteamNodeInfoResponse) Reset() {
-	*x = SteamNodeInfoResponse{}
-	mi := &file___proto_msgTypes[5]
+func (x *ViewChange) Reset() {
+	*x = ViewChange{}
+	mi := &file___proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SteamNodeInfoResponse) String() string {
+func (x *ViewChange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SteamNodeInfoResponse) ProtoMessage() {}
+func (*ViewChange) ProtoMessage() {}
 
-func (x *SteamNodeInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file___proto_msgTypes[5]
+func (x *ViewChange) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -354,63 +552,1028 @@ func (x *SteamNodeInfoResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SteamNodeInfoResponse.ProtoReflect.Descriptor instead.
-func (*SteamNodeInfoResponse) Descriptor() ([]byte, []int) {
-	return file___proto_rawDescGZIP(), []int{5}
+// Deprecated: Use ViewChange.ProtoReflect.Descriptor instead.
+func (*ViewChange) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{6}
 }
 
-func (x *SteamNodeInfoResponse) GetNodeId() string {
+func (x *ViewChange) GetNodeId() string {
 	if x != nil {
 		return x.NodeId
 	}
 	return ""
 }
 
-func (x *SteamNodeInfoResponse) GetNodeStatus() string {
+func (x *ViewChange) GetHeight() uint64 {
 	if x != nil {
-		return x.NodeStatus
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ViewChange) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *ViewChange) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type PbftMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Height        uint64                 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	View          uint32                 `protobuf:"varint,4,opt,name=view,proto3" json:"view,omitempty"`
+	BlockHash     []byte                 `protobuf:"bytes,5,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PbftMessage) Reset() {
+	*x = PbftMessage{}
+	mi := &file___proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PbftMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PbftMessage) ProtoMessage() {}
+
+func (x *PbftMessage) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PbftMessage.ProtoReflect.Descriptor instead.
+func (*PbftMessage) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PbftMessage) GetType() string {
+	if x != nil {
+		return x.Type
 	}
 	return ""
 }
 
-var File___proto protoreflect.FileDescriptor
+func (x *PbftMessage) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
 
-const file___proto_rawDesc = "" +
-	"\n" +
-	"\x06.proto\x12\x02pb\"\a\n" +
-	"\x05Empty\"\xb3\x01\n" +
-	"\vTransaction\x12\x16\n" +
-	"\x06sender\x18\x01 \x01(\fR\x06sender\x12\x1a\n" +
-	"\breceiver\x18\x02 \x01(\fR\breceiver\x12\x16\n" +
-	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x1c\n" +
-	"\tsignature\x18\x05 \x01(\fR\tsignature\x12\x1c\n" +
-	"\tpublicKey\x18\x06 \x01(\fR\tpublicKey\"\xdc\x01\n" +
-	"\x05Block\x123\n" +
-	"\ftransactions\x18\x01 \x03(\v2\x0f.pb.TransactionR\ftransactions\x12(\n" +
-	"\x10merkle_root_hash\x18\x02 \x01(\fR\x0emerkleRootHash\x12.\n" +
-	"\x13previous_block_hash\x18\x03 \x01(\fR\x11previousBlockHash\x12,\n" +
-	"\x12current_block_hash\x18\x04 \x01(\fR\x10currentBlockHash\x12\x16\n" +
-	"\x06height\x18\x05 \x01(\x04R\x06height\"[\n" +
-	"\x05AVote\x12\x18\n" +
-	"\aapprove\x18\x01 \x01(\bR\aapprove\x12\x16\n" +
-	"\x06nodeId\x18\x02 \x01(\tR\x06nodeId\x12 \n" +
-	"\vblockHeight\x18\x03 \x01(\x04R\vblockHeight\"%\n" +
-	"\vBlockHeight\x12\x16\n" +
-	"\x06height\x18\x01 \x01(\x04R\x06height\"O\n" +
-	"\x15SteamNodeInfoResponse\x12\x16\n" +
-	"\x06nodeId\x18\x01 \x01(\tR\x06nodeId\x12\x1e\n" +
-	"\n" +
-	"nodeStatus\x18\x02 \x01(\tR\n" +
-	"nodeStatus2\xae\x02\n" +
-	"\n" +
-	"Blockchain\x12-\n" +
-	"\x0fSendTransaction\x12\x0f.pb.Transaction\x1a\t.pb.Empty\x12$\n" +
-	"\fProposeBlock\x12\t.pb.Block\x1a\t.pb.Empty\x12\x1c\n" +
-	"\x04Vote\x12\t.pb.AVote\x1a\t.pb.Empty\x12&\n" +
-	"\bGetBlock\x12\x0f.pb.BlockHeight\x1a\t.pb.Block\x12&\n" +
-	"\x0eGetLatestBlock\x12\t.pb.Empty\x1a\t.pb.Block\x12#\n" +
-	"\vCommitBlock\x12\t.pb.Empty\x1a\t.pb.Empty\x128\n" +
+func (x *PbftMessage) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PbftMessage) GetView() uint32 {
+	if x != nil {
+		return x.View
+	}
+	return 0
+}
+
+func (x *PbftMessage) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *PbftMessage) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ConsensusMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsensusMessage) Reset() {
+	*x = ConsensusMessage{}
+	mi := &file___proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsensusMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsensusMessage) ProtoMessage() {}
+
+func (x *ConsensusMessage) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsensusMessage.ProtoReflect.Descriptor instead.
+func (*ConsensusMessage) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ConsensusMessage) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ConsensusMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type Evidence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	NodeId        string                 `protobuf:"bytes,2,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	Height        uint64                 `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Round         uint32                 `protobuf:"varint,4,opt,name=round,proto3" json:"round,omitempty"`
+	VoteA         *AVote                 `protobuf:"bytes,5,opt,name=voteA,proto3" json:"voteA,omitempty"`
+	VoteB         *AVote                 `protobuf:"bytes,6,opt,name=voteB,proto3" json:"voteB,omitempty"`
+	MessageA      *PbftMessage           `protobuf:"bytes,7,opt,name=messageA,proto3" json:"messageA,omitempty"`
+	MessageB      *PbftMessage           `protobuf:"bytes,8,opt,name=messageB,proto3" json:"messageB,omitempty"`
+	BlockA        *Block                 `protobuf:"bytes,9,opt,name=blockA,proto3" json:"blockA,omitempty"`
+	BlockB        *Block                 `protobuf:"bytes,10,opt,name=blockB,proto3" json:"blockB,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Evidence) Reset() {
+	*x = Evidence{}
+	mi := &file___proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Evidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Evidence) ProtoMessage() {}
+
+func (x *Evidence) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Evidence.ProtoReflect.Descriptor instead.
+func (*Evidence) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Evidence) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Evidence) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Evidence) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Evidence) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *Evidence) GetVoteA() *AVote {
+	if x != nil {
+		return x.VoteA
+	}
+	return nil
+}
+
+func (x *Evidence) GetVoteB() *AVote {
+	if x != nil {
+		return x.VoteB
+	}
+	return nil
+}
+
+func (x *Evidence) GetMessageA() *PbftMessage {
+	if x != nil {
+		return x.MessageA
+	}
+	return nil
+}
+
+func (x *Evidence) GetMessageB() *PbftMessage {
+	if x != nil {
+		return x.MessageB
+	}
+	return nil
+}
+
+func (x *Evidence) GetBlockA() *Block {
+	if x != nil {
+		return x.BlockA
+	}
+	return nil
+}
+
+func (x *Evidence) GetBlockB() *Block {
+	if x != nil {
+		return x.BlockB
+	}
+	return nil
+}
+
+type QuorumCertificate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Height        uint64                 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	BlockHash     []byte                 `protobuf:"bytes,2,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Votes         []*AVote               `protobuf:"bytes,3,rep,name=votes,proto3" json:"votes,omitempty"`
+	Commits       []*PbftMessage         `protobuf:"bytes,4,rep,name=commits,proto3" json:"commits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuorumCertificate) Reset() {
+	*x = QuorumCertificate{}
+	mi := &file___proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuorumCertificate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuorumCertificate) ProtoMessage() {}
+
+func (x *QuorumCertificate) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuorumCertificate.ProtoReflect.Descriptor instead.
+func (*QuorumCertificate) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QuorumCertificate) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *QuorumCertificate) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *QuorumCertificate) GetVotes() []*AVote {
+	if x != nil {
+		return x.Votes
+	}
+	return nil
+}
+
+func (x *QuorumCertificate) GetCommits() []*PbftMessage {
+	if x != nil {
+		return x.Commits
+	}
+	return nil
+}
+
+type WalEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Height        uint64                 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round         uint32                 `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Proposal      *Block                 `protobuf:"bytes,4,opt,name=proposal,proto3" json:"proposal,omitempty"`
+	Vote          *AVote                 `protobuf:"bytes,5,opt,name=vote,proto3" json:"vote,omitempty"`
+	Message       *PbftMessage           `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WalEntry) Reset() {
+	*x = WalEntry{}
+	mi := &file___proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WalEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WalEntry) ProtoMessage() {}
+
+func (x *WalEntry) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WalEntry.ProtoReflect.Descriptor instead.
+func (*WalEntry) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WalEntry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WalEntry) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *WalEntry) GetRound() uint32 {
+	if x != nil {
+		return x.Round
+	}
+	return 0
+}
+
+func (x *WalEntry) GetProposal() *Block {
+	if x != nil {
+		return x.Proposal
+	}
+	return nil
+}
+
+func (x *WalEntry) GetVote() *AVote {
+	if x != nil {
+		return x.Vote
+	}
+	return nil
+}
+
+func (x *WalEntry) GetMessage() *PbftMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type BlockHeight struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Height        uint64                 `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockHeight) Reset() {
+	*x = BlockHeight{}
+	mi := &file___proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockHeight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHeight) ProtoMessage() {}
+
+func (x *BlockHeight) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHeight.ProtoReflect.Descriptor instead.
+func (*BlockHeight) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BlockHeight) GetHeight() uint64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type BlockHash struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockHash) Reset() {
+	*x = BlockHash{}
+	mi := &file___proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockHash) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHash) ProtoMessage() {}
+
+func (x *BlockHash) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHash.ProtoReflect.Descriptor instead.
+func (*BlockHash) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BlockHash) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+type MempoolEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Transaction   *Transaction           `protobuf:"bytes,2,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MempoolEntry) Reset() {
+	*x = MempoolEntry{}
+	mi := &file___proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MempoolEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MempoolEntry) ProtoMessage() {}
+
+func (x *MempoolEntry) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MempoolEntry.ProtoReflect.Descriptor instead.
+func (*MempoolEntry) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{14}
+}
+
+func (x *MempoolEntry) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *MempoolEntry) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+type GetMempoolRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        uint32                 `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         uint32                 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Sender        []byte                 `protobuf:"bytes,3,opt,name=sender,proto3" json:"sender,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMempoolRequest) Reset() {
+	*x = GetMempoolRequest{}
+	mi := &file___proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMempoolRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMempoolRequest) ProtoMessage() {}
+
+func (x *GetMempoolRequest) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMempoolRequest.ProtoReflect.Descriptor instead.
+func (*GetMempoolRequest) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetMempoolRequest) GetOffset() uint32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *GetMempoolRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *GetMempoolRequest) GetSender() []byte {
+	if x != nil {
+		return x.Sender
+	}
+	return nil
+}
+
+type GetMempoolResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*MempoolEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Total         uint32                 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMempoolResponse) Reset() {
+	*x = GetMempoolResponse{}
+	mi := &file___proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMempoolResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMempoolResponse) ProtoMessage() {}
+
+func (x *GetMempoolResponse) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMempoolResponse.ProtoReflect.Descriptor instead.
+func (*GetMempoolResponse) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetMempoolResponse) GetEntries() []*MempoolEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *GetMempoolResponse) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type MempoolEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Entry         *MempoolEntry          `protobuf:"bytes,2,opt,name=entry,proto3" json:"entry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MempoolEvent) Reset() {
+	*x = MempoolEvent{}
+	mi := &file___proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MempoolEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MempoolEvent) ProtoMessage() {}
+
+func (x *MempoolEvent) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MempoolEvent.ProtoReflect.Descriptor instead.
+func (*MempoolEvent) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{17}
+}
+
+func (x *MempoolEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *MempoolEvent) GetEntry() *MempoolEntry {
+	if x != nil {
+		return x.Entry
+	}
+	return nil
+}
+
+type Account struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       []byte                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Account) Reset() {
+	*x = Account{}
+	mi := &file___proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Account) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Account) ProtoMessage() {}
+
+func (x *Account) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Account.ProtoReflect.Descriptor instead.
+func (*Account) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Account) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+type AccountNonce struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CommittedNonce uint64                 `protobuf:"varint,1,opt,name=committedNonce,proto3" json:"committedNonce,omitempty"`
+	PendingNonce   uint64                 `protobuf:"varint,2,opt,name=pendingNonce,proto3" json:"pendingNonce,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AccountNonce) Reset() {
+	*x = AccountNonce{}
+	mi := &file___proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccountNonce) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountNonce) ProtoMessage() {}
+
+func (x *AccountNonce) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountNonce.ProtoReflect.Descriptor instead.
+func (*AccountNonce) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AccountNonce) GetCommittedNonce() uint64 {
+	if x != nil {
+		return x.CommittedNonce
+	}
+	return 0
+}
+
+func (x *AccountNonce) GetPendingNonce() uint64 {
+	if x != nil {
+		return x.PendingNonce
+	}
+	return 0
+}
+
+type SteamNodeInfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	NodeStatus    string                 `protobuf:"bytes,2,opt,name=nodeStatus,proto3" json:"nodeStatus,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SteamNodeInfoResponse) Reset() {
+	*x = SteamNodeInfoResponse{}
+	mi := &file___proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SteamNodeInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SteamNodeInfoResponse) ProtoMessage() {}
+
+func (x *SteamNodeInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file___proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SteamNodeInfoResponse.ProtoReflect.Descriptor instead.
+func (*SteamNodeInfoResponse) Descriptor() ([]byte, []int) {
+	return file___proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SteamNodeInfoResponse) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *SteamNodeInfoResponse) GetNodeStatus() string {
+	if x != nil {
+		return x.NodeStatus
+	}
+	return ""
+}
+
+var File___proto protoreflect.FileDescriptor
+
+const file___proto_rawDesc = "" +
+	"\n" +
+	"\x06.proto\x12\x02pb\"\a\n" +
+	"\x05Empty\"\xbf\x02\n" +
+	"\vTransaction\x12\x16\n" +
+	"\x06sender\x18\x01 \x01(\fR\x06sender\x12\x1a\n" +
+	"\breceiver\x18\x02 \x01(\fR\breceiver\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x1c\n" +
+	"\tsignature\x18\x05 \x01(\fR\tsignature\x12\x1c\n" +
+	"\tpublicKey\x18\x06 \x01(\fR\tpublicKey\x12\x10\n" +
+	"\x03fee\x18\a \x01(\x01R\x03fee\x12\x14\n" +
+	"\x05nonce\x18\b \x01(\x04R\x05nonce\x12\x12\n" +
+	"\x04type\x18\t \x01(\tR\x04type\x12!\n" +
+	"\fvalidator_id\x18\n" +
+	" \x01(\tR\vvalidatorId\x12+\n" +
+	"\x11validator_address\x18\v \x01(\tR\x10validatorAddress\"G\n" +
+	"\x10TransactionBatch\x123\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x0f.pb.TransactionR\ftransactions\"\xf9\x03\n" +
+	"\x05Block\x123\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x0f.pb.TransactionR\ftransactions\x12(\n" +
+	"\x10merkle_root_hash\x18\x02 \x01(\fR\x0emerkleRootHash\x12.\n" +
+	"\x13previous_block_hash\x18\x03 \x01(\fR\x11previousBlockHash\x12,\n" +
+	"\x12current_block_hash\x18\x04 \x01(\fR\x10currentBlockHash\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x04R\x06height\x12\x1f\n" +
+	"\vproposer_id\x18\x06 \x01(\tR\n" +
+	"proposerId\x12-\n" +
+	"\x12proposer_signature\x18\a \x01(\fR\x11proposerSignature\x12\x14\n" +
+	"\x05round\x18\b \x01(\rR\x05round\x127\n" +
+	"\vcertificate\x18\t \x01(\v2\x15.pb.QuorumCertificateR\vcertificate\x12\x1c\n" +
+	"\ttimestamp\x18\n" +
+	" \x01(\x03R\ttimestamp\x12\x14\n" +
+	"\x05nonce\x18\v \x01(\x04R\x05nonce\x12\x1e\n" +
+	"\n" +
+	"difficulty\x18\f \x01(\rR\n" +
+	"difficulty\x12(\n" +
+	"\bevidence\x18\r \x03(\v2\f.pb.EvidenceR\bevidence\"\xad\x01\n" +
+	"\x05AVote\x12\x18\n" +
+	"\aapprove\x18\x01 \x01(\bR\aapprove\x12\x16\n" +
+	"\x06nodeId\x18\x02 \x01(\tR\x06nodeId\x12 \n" +
+	"\vblockHeight\x18\x03 \x01(\x04R\vblockHeight\x12\x1c\n" +
+	"\tblockHash\x18\x04 \x01(\fR\tblockHash\x12\x1c\n" +
+	"\tsignature\x18\x05 \x01(\fR\tsignature\x12\x14\n" +
+	"\x05round\x18\x06 \x01(\rR\x05round\"o\n" +
+	"\tHeartbeat\x12\x16\n" +
+	"\x06nodeId\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x04R\x06height\x12\x14\n" +
+	"\x05round\x18\x03 \x01(\rR\x05round\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\fR\tsignature\"p\n" +
+	"\n" +
+	"ViewChange\x12\x16\n" +
+	"\x06nodeId\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x04R\x06height\x12\x14\n" +
+	"\x05round\x18\x03 \x01(\rR\x05round\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\fR\tsignature\"\xa1\x01\n" +
+	"\vPbftMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06nodeId\x18\x02 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x04R\x06height\x12\x12\n" +
+	"\x04view\x18\x04 \x01(\rR\x04view\x12\x1c\n" +
+	"\tblockHash\x18\x05 \x01(\fR\tblockHash\x12\x1c\n" +
+	"\tsignature\x18\x06 \x01(\fR\tsignature\"@\n" +
+	"\x10ConsensusMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\"\xc6\x02\n" +
+	"\bEvidence\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06nodeId\x18\x02 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06height\x18\x03 \x01(\x04R\x06height\x12\x14\n" +
+	"\x05round\x18\x04 \x01(\rR\x05round\x12\x1f\n" +
+	"\x05voteA\x18\x05 \x01(\v2\t.pb.AVoteR\x05voteA\x12\x1f\n" +
+	"\x05voteB\x18\x06 \x01(\v2\t.pb.AVoteR\x05voteB\x12+\n" +
+	"\bmessageA\x18\a \x01(\v2\x0f.pb.PbftMessageR\bmessageA\x12+\n" +
+	"\bmessageB\x18\b \x01(\v2\x0f.pb.PbftMessageR\bmessageB\x12!\n" +
+	"\x06blockA\x18\t \x01(\v2\t.pb.BlockR\x06blockA\x12!\n" +
+	"\x06blockB\x18\n" +
+	" \x01(\v2\t.pb.BlockR\x06blockB\"\x95\x01\n" +
+	"\x11QuorumCertificate\x12\x16\n" +
+	"\x06height\x18\x01 \x01(\x04R\x06height\x12\x1c\n" +
+	"\tblockHash\x18\x02 \x01(\fR\tblockHash\x12\x1f\n" +
+	"\x05votes\x18\x03 \x03(\v2\t.pb.AVoteR\x05votes\x12)\n" +
+	"\acommits\x18\x04 \x03(\v2\x0f.pb.PbftMessageR\acommits\"\xbd\x01\n" +
+	"\bWalEntry\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x04R\x06height\x12\x14\n" +
+	"\x05round\x18\x03 \x01(\rR\x05round\x12%\n" +
+	"\bproposal\x18\x04 \x01(\v2\t.pb.BlockR\bproposal\x12\x1d\n" +
+	"\x04vote\x18\x05 \x01(\v2\t.pb.AVoteR\x04vote\x12)\n" +
+	"\amessage\x18\x06 \x01(\v2\x0f.pb.PbftMessageR\amessage\"%\n" +
+	"\vBlockHeight\x12\x16\n" +
+	"\x06height\x18\x01 \x01(\x04R\x06height\"\x1f\n" +
+	"\tBlockHash\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\fR\x04hash\"U\n" +
+	"\fMempoolEntry\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\fR\x04hash\x121\n" +
+	"\vtransaction\x18\x02 \x01(\v2\x0f.pb.TransactionR\vtransaction\"Y\n" +
+	"\x11GetMempoolRequest\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\rR\x06offset\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\x12\x16\n" +
+	"\x06sender\x18\x03 \x01(\fR\x06sender\"V\n" +
+	"\x12GetMempoolResponse\x12*\n" +
+	"\aentries\x18\x01 \x03(\v2\x10.pb.MempoolEntryR\aentries\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\rR\x05total\"J\n" +
+	"\fMempoolEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12&\n" +
+	"\x05entry\x18\x02 \x01(\v2\x10.pb.MempoolEntryR\x05entry\"#\n" +
+	"\aAccount\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\fR\aaddress\"Z\n" +
+	"\fAccountNonce\x12&\n" +
+	"\x0ecommittedNonce\x18\x01 \x01(\x04R\x0ecommittedNonce\x12\"\n" +
+	"\fpendingNonce\x18\x02 \x01(\x04R\fpendingNonce\"O\n" +
+	"\x15SteamNodeInfoResponse\x12\x16\n" +
+	"\x06nodeId\x18\x01 \x01(\tR\x06nodeId\x12\x1e\n" +
+	"\n" +
+	"nodeStatus\x18\x02 \x01(\tR\n" +
+	"nodeStatus2\x83\x05\n" +
+	"\n" +
+	"Blockchain\x12-\n" +
+	"\x0fSendTransaction\x12\x0f.pb.Transaction\x1a\t.pb.Empty\x125\n" +
+	"\x12GossipTransactions\x12\x14.pb.TransactionBatch\x1a\t.pb.Empty\x12$\n" +
+	"\fProposeBlock\x12\t.pb.Block\x1a\t.pb.Empty\x12&\n" +
+	"\bGetBlock\x12\x0f.pb.BlockHeight\x1a\t.pb.Block\x12*\n" +
+	"\x0eGetBlockByHash\x12\r.pb.BlockHash\x1a\t.pb.Block\x12&\n" +
+	"\x0eGetLatestBlock\x12\t.pb.Empty\x1a\t.pb.Block\x12/\n" +
+	"\vCommitBlock\x12\x15.pb.QuorumCertificate\x1a\t.pb.Empty\x127\n" +
+	"\x14SendConsensusMessage\x12\x14.pb.ConsensusMessage\x1a\t.pb.Empty\x12'\n" +
+	"\fSendEvidence\x12\f.pb.Evidence\x1a\t.pb.Empty\x12;\n" +
+	"\n" +
+	"GetMempool\x12\x15.pb.GetMempoolRequest\x1a\x16.pb.GetMempoolResponse\x121\n" +
+	"\x10SubscribeMempool\x12\t.pb.Empty\x1a\x10.pb.MempoolEvent0\x01\x120\n" +
+	"\x0fGetAccountNonce\x12\v.pb.Account\x1a\x10.pb.AccountNonce\x128\n" +
 	"\x0eStreamNodeInfo\x12\t.pb.Empty\x1a\x19.pb.SteamNodeInfoResponse0\x01B\x11Z\x0f./pkg/p2p/pb/pbb\x06proto3"
 
 var (
@@ -425,36 +1588,80 @@ func file___proto_rawDescGZIP() []byte {
 	return file___proto_rawDescData
 }
 
-var file___proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file___proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file___proto_goTypes = []any{
 	(*Empty)(nil),                 // 0: pb.Empty
 	(*Transaction)(nil),           // 1: pb.Transaction
-	(*Block)(nil),                 // 2: pb.Block
-	(*AVote)(nil),                 // 3: pb.AVote
-	(*BlockHeight)(nil),           // 4: pb.BlockHeight
-	(*SteamNodeInfoResponse)(nil), // 5: pb.SteamNodeInfoResponse
+	(*TransactionBatch)(nil),      // 2: pb.TransactionBatch
+	(*Block)(nil),                 // 3: pb.Block
+	(*AVote)(nil),                 // 4: pb.AVote
+	(*Heartbeat)(nil),             // 5: pb.Heartbeat
+	(*ViewChange)(nil),            // 6: pb.ViewChange
+	(*PbftMessage)(nil),           // 7: pb.PbftMessage
+	(*ConsensusMessage)(nil),      // 8: pb.ConsensusMessage
+	(*Evidence)(nil),              // 9: pb.Evidence
+	(*QuorumCertificate)(nil),     // 10: pb.QuorumCertificate
+	(*WalEntry)(nil),              // 11: pb.WalEntry
+	(*BlockHeight)(nil),           // 12: pb.BlockHeight
+	(*BlockHash)(nil),             // 13: pb.BlockHash
+	(*MempoolEntry)(nil),          // 14: pb.MempoolEntry
+	(*GetMempoolRequest)(nil),     // 15: pb.GetMempoolRequest
+	(*GetMempoolResponse)(nil),    // 16: pb.GetMempoolResponse
+	(*MempoolEvent)(nil),          // 17: pb.MempoolEvent
+	(*Account)(nil),               // 18: pb.Account
+	(*AccountNonce)(nil),          // 19: pb.AccountNonce
+	(*SteamNodeInfoResponse)(nil), // 20: pb.SteamNodeInfoResponse
 }
 var file___proto_depIdxs = []int32{
-	1, // 0: pb.Block.transactions:type_name -> pb.Transaction
-	1, // 1: pb.Blockchain.SendTransaction:input_type -> pb.Transaction
-	2, // 2: pb.Blockchain.ProposeBlock:input_type -> pb.Block
-	3, // 3: pb.Blockchain.Vote:input_type -> pb.AVote
-	4, // 4: pb.Blockchain.GetBlock:input_type -> pb.BlockHeight
-	0, // 5: pb.Blockchain.GetLatestBlock:input_type -> pb.Empty
-	0, // 6: pb.Blockchain.CommitBlock:input_type -> pb.Empty
-	0, // 7: pb.Blockchain.StreamNodeInfo:input_type -> pb.Empty
-	0, // 8: pb.Blockchain.SendTransaction:output_type -> pb.Empty
-	0, // 9: pb.Blockchain.ProposeBlock:output_type -> pb.Empty
-	0, // 10: pb.Blockchain.Vote:output_type -> pb.Empty
-	2, // 11: pb.Blockchain.GetBlock:output_type -> pb.Block
-	2, // 12: pb.Blockchain.GetLatestBlock:output_type -> pb.Block
-	0, // 13: pb.Blockchain.CommitBlock:output_type -> pb.Empty
-	5, // 14: pb.Blockchain.StreamNodeInfo:output_type -> pb.SteamNodeInfoResponse
-	8, // [8:15] is the sub-list for method output_type
-	1, // [1:8] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	1,  // 0: pb.TransactionBatch.transactions:type_name -> pb.Transaction
+	1,  // 1: pb.Block.transactions:type_name -> pb.Transaction
+	10, // 2: pb.Block.certificate:type_name -> pb.QuorumCertificate
+	9,  // 3: pb.Block.evidence:type_name -> pb.Evidence
+	4,  // 4: pb.Evidence.voteA:type_name -> pb.AVote
+	4,  // 5: pb.Evidence.voteB:type_name -> pb.AVote
+	7,  // 6: pb.Evidence.messageA:type_name -> pb.PbftMessage
+	7,  // 7: pb.Evidence.messageB:type_name -> pb.PbftMessage
+	3,  // 8: pb.Evidence.blockA:type_name -> pb.Block
+	3,  // 9: pb.Evidence.blockB:type_name -> pb.Block
+	4,  // 10: pb.QuorumCertificate.votes:type_name -> pb.AVote
+	7,  // 11: pb.QuorumCertificate.commits:type_name -> pb.PbftMessage
+	3,  // 12: pb.WalEntry.proposal:type_name -> pb.Block
+	4,  // 13: pb.WalEntry.vote:type_name -> pb.AVote
+	7,  // 14: pb.WalEntry.message:type_name -> pb.PbftMessage
+	1,  // 15: pb.MempoolEntry.transaction:type_name -> pb.Transaction
+	14, // 16: pb.GetMempoolResponse.entries:type_name -> pb.MempoolEntry
+	14, // 17: pb.MempoolEvent.entry:type_name -> pb.MempoolEntry
+	1,  // 18: pb.Blockchain.SendTransaction:input_type -> pb.Transaction
+	2,  // 19: pb.Blockchain.GossipTransactions:input_type -> pb.TransactionBatch
+	3,  // 20: pb.Blockchain.ProposeBlock:input_type -> pb.Block
+	12, // 21: pb.Blockchain.GetBlock:input_type -> pb.BlockHeight
+	13, // 22: pb.Blockchain.GetBlockByHash:input_type -> pb.BlockHash
+	0,  // 23: pb.Blockchain.GetLatestBlock:input_type -> pb.Empty
+	10, // 24: pb.Blockchain.CommitBlock:input_type -> pb.QuorumCertificate
+	8,  // 25: pb.Blockchain.SendConsensusMessage:input_type -> pb.ConsensusMessage
+	9,  // 26: pb.Blockchain.SendEvidence:input_type -> pb.Evidence
+	15, // 27: pb.Blockchain.GetMempool:input_type -> pb.GetMempoolRequest
+	0,  // 28: pb.Blockchain.SubscribeMempool:input_type -> pb.Empty
+	18, // 29: pb.Blockchain.GetAccountNonce:input_type -> pb.Account
+	0,  // 30: pb.Blockchain.StreamNodeInfo:input_type -> pb.Empty
+	0,  // 31: pb.Blockchain.SendTransaction:output_type -> pb.Empty
+	0,  // 32: pb.Blockchain.GossipTransactions:output_type -> pb.Empty
+	0,  // 33: pb.Blockchain.ProposeBlock:output_type -> pb.Empty
+	3,  // 34: pb.Blockchain.GetBlock:output_type -> pb.Block
+	3,  // 35: pb.Blockchain.GetBlockByHash:output_type -> pb.Block
+	3,  // 36: pb.Blockchain.GetLatestBlock:output_type -> pb.Block
+	0,  // 37: pb.Blockchain.CommitBlock:output_type -> pb.Empty
+	0,  // 38: pb.Blockchain.SendConsensusMessage:output_type -> pb.Empty
+	0,  // 39: pb.Blockchain.SendEvidence:output_type -> pb.Empty
+	16, // 40: pb.Blockchain.GetMempool:output_type -> pb.GetMempoolResponse
+	17, // 41: pb.Blockchain.SubscribeMempool:output_type -> pb.MempoolEvent
+	19, // 42: pb.Blockchain.GetAccountNonce:output_type -> pb.AccountNonce
+	20, // 43: pb.Blockchain.StreamNodeInfo:output_type -> pb.SteamNodeInfoResponse
+	31, // [31:44] is the sub-list for method output_type
+	18, // [18:31] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file___proto_init() }
@@ -468,7 +1675,7 @@ func file___proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file___proto_rawDesc), len(file___proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
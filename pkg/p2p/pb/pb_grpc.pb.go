@@ -19,13 +19,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Blockchain_SendTransaction_FullMethodName = "/pb.Blockchain/SendTransaction"
-	Blockchain_ProposeBlock_FullMethodName    = "/pb.Blockchain/ProposeBlock"
-	Blockchain_Vote_FullMethodName            = "/pb.Blockchain/Vote"
-	Blockchain_GetBlock_FullMethodName        = "/pb.Blockchain/GetBlock"
-	Blockchain_GetLatestBlock_FullMethodName  = "/pb.Blockchain/GetLatestBlock"
-	Blockchain_CommitBlock_FullMethodName     = "/pb.Blockchain/CommitBlock"
-	Blockchain_StreamNodeInfo_FullMethodName  = "/pb.Blockchain/StreamNodeInfo"
+	Blockchain_SendTransaction_FullMethodName      = "/pb.Blockchain/SendTransaction"
+	Blockchain_GossipTransactions_FullMethodName   = "/pb.Blockchain/GossipTransactions"
+	Blockchain_ProposeBlock_FullMethodName         = "/pb.Blockchain/ProposeBlock"
+	Blockchain_GetBlock_FullMethodName             = "/pb.Blockchain/GetBlock"
+	Blockchain_GetBlockByHash_FullMethodName       = "/pb.Blockchain/GetBlockByHash"
+	Blockchain_GetLatestBlock_FullMethodName       = "/pb.Blockchain/GetLatestBlock"
+	Blockchain_CommitBlock_FullMethodName          = "/pb.Blockchain/CommitBlock"
+	Blockchain_SendConsensusMessage_FullMethodName = "/pb.Blockchain/SendConsensusMessage"
+	Blockchain_SendEvidence_FullMethodName         = "/pb.Blockchain/SendEvidence"
+	Blockchain_GetMempool_FullMethodName           = "/pb.Blockchain/GetMempool"
+	Blockchain_SubscribeMempool_FullMethodName     = "/pb.Blockchain/SubscribeMempool"
+	Blockchain_GetAccountNonce_FullMethodName      = "/pb.Blockchain/GetAccountNonce"
+	Blockchain_StreamNodeInfo_FullMethodName       = "/pb.Blockchain/StreamNodeInfo"
 )
 
 // BlockchainClient is the client API for Blockchain service.
@@ -33,11 +39,17 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BlockchainClient interface {
 	SendTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*Empty, error)
+	GossipTransactions(ctx context.Context, in *TransactionBatch, opts ...grpc.CallOption) (*Empty, error)
 	ProposeBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Empty, error)
-	Vote(ctx context.Context, in *AVote, opts ...grpc.CallOption) (*Empty, error)
 	GetBlock(ctx context.Context, in *BlockHeight, opts ...grpc.CallOption) (*Block, error)
+	GetBlockByHash(ctx context.Context, in *BlockHash, opts ...grpc.CallOption) (*Block, error)
 	GetLatestBlock(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Block, error)
-	CommitBlock(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	CommitBlock(ctx context.Context, in *QuorumCertificate, opts ...grpc.CallOption) (*Empty, error)
+	SendConsensusMessage(ctx context.Context, in *ConsensusMessage, opts ...grpc.CallOption) (*Empty, error)
+	SendEvidence(ctx context.Context, in *Evidence, opts ...grpc.CallOption) (*Empty, error)
+	GetMempool(ctx context.Context, in *GetMempoolRequest, opts ...grpc.CallOption) (*GetMempoolResponse, error)
+	SubscribeMempool(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MempoolEvent], error)
+	GetAccountNonce(ctx context.Context, in *Account, opts ...grpc.CallOption) (*AccountNonce, error)
 	StreamNodeInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SteamNodeInfoResponse], error)
 }
 
@@ -59,20 +71,20 @@ func (c *blockchainClient) SendTransaction(ctx context.Context, in *Transaction,
 	return out, nil
 }
 
-func (c *blockchainClient) ProposeBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Empty, error) {
+func (c *blockchainClient) GossipTransactions(ctx context.Context, in *TransactionBatch, opts ...grpc.CallOption) (*Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Empty)
-	err := c.cc.Invoke(ctx, Blockchain_ProposeBlock_FullMethodName, in, out, cOpts...)
+	err := c.cc.Invoke(ctx, Blockchain_GossipTransactions_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *blockchainClient) Vote(ctx context.Context, in *AVote, opts ...grpc.CallOption) (*Empty, error) {
+func (c *blockchainClient) ProposeBlock(ctx context.Context, in *Block, opts ...grpc.CallOption) (*Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Empty)
-	err := c.cc.Invoke(ctx, Blockchain_Vote_FullMethodName, in, out, cOpts...)
+	err := c.cc.Invoke(ctx, Blockchain_ProposeBlock_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +101,16 @@ func (c *blockchainClient) GetBlock(ctx context.Context, in *BlockHeight, opts .
 	return out, nil
 }
 
+func (c *blockchainClient) GetBlockByHash(ctx context.Context, in *BlockHash, opts ...grpc.CallOption) (*Block, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Block)
+	err := c.cc.Invoke(ctx, Blockchain_GetBlockByHash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *blockchainClient) GetLatestBlock(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Block, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Block)
@@ -99,7 +121,7 @@ func (c *blockchainClient) GetLatestBlock(ctx context.Context, in *Empty, opts .
 	return out, nil
 }
 
-func (c *blockchainClient) CommitBlock(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+func (c *blockchainClient) CommitBlock(ctx context.Context, in *QuorumCertificate, opts ...grpc.CallOption) (*Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, Blockchain_CommitBlock_FullMethodName, in, out, cOpts...)
@@ -109,9 +131,68 @@ func (c *blockchainClient) CommitBlock(ctx context.Context, in *Empty, opts ...g
 	return out, nil
 }
 
+func (c *blockchainClient) SendConsensusMessage(ctx context.Context, in *ConsensusMessage, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Blockchain_SendConsensusMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) SendEvidence(ctx context.Context, in *Evidence, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Blockchain_SendEvidence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) GetMempool(ctx context.Context, in *GetMempoolRequest, opts ...grpc.CallOption) (*GetMempoolResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMempoolResponse)
+	err := c.cc.Invoke(ctx, Blockchain_GetMempool_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockchainClient) SubscribeMempool(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MempoolEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Blockchain_ServiceDesc.Streams[0], Blockchain_SubscribeMempool_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Empty, MempoolEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Blockchain_SubscribeMempoolClient = grpc.ServerStreamingClient[MempoolEvent]
+
+func (c *blockchainClient) GetAccountNonce(ctx context.Context, in *Account, opts ...grpc.CallOption) (*AccountNonce, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountNonce)
+	err := c.cc.Invoke(ctx, Blockchain_GetAccountNonce_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *blockchainClient) StreamNodeInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SteamNodeInfoResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	stream, err := c.cc.NewStream(ctx, &Blockchain_ServiceDesc.Streams[0], Blockchain_StreamNodeInfo_FullMethodName, cOpts...)
+	stream, err := c.cc.NewStream(ctx, &Blockchain_ServiceDesc.Streams[1], Blockchain_StreamNodeInfo_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -133,11 +214,17 @@ type Blockchain_StreamNodeInfoClient = grpc.ServerStreamingClient[SteamNodeInfoR
 // for forward compatibility.
 type BlockchainServer interface {
 	SendTransaction(context.Context, *Transaction) (*Empty, error)
+	GossipTransactions(context.Context, *TransactionBatch) (*Empty, error)
 	ProposeBlock(context.Context, *Block) (*Empty, error)
-	Vote(context.Context, *AVote) (*Empty, error)
 	GetBlock(context.Context, *BlockHeight) (*Block, error)
+	GetBlockByHash(context.Context, *BlockHash) (*Block, error)
 	GetLatestBlock(context.Context, *Empty) (*Block, error)
-	CommitBlock(context.Context, *Empty) (*Empty, error)
+	CommitBlock(context.Context, *QuorumCertificate) (*Empty, error)
+	SendConsensusMessage(context.Context, *ConsensusMessage) (*Empty, error)
+	SendEvidence(context.Context, *Evidence) (*Empty, error)
+	GetMempool(context.Context, *GetMempoolRequest) (*GetMempoolResponse, error)
+	SubscribeMempool(*Empty, grpc.ServerStreamingServer[MempoolEvent]) error
+	GetAccountNonce(context.Context, *Account) (*AccountNonce, error)
 	StreamNodeInfo(*Empty, grpc.ServerStreamingServer[SteamNodeInfoResponse]) error
 	mustEmbedUnimplementedBlockchainServer()
 }
@@ -152,21 +239,39 @@ type UnimplementedBlockchainServer struct{}
 func (UnimplementedBlockchainServer) SendTransaction(context.Context, *Transaction) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendTransaction not implemented")
 }
+func (UnimplementedBlockchainServer) GossipTransactions(context.Context, *TransactionBatch) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GossipTransactions not implemented")
+}
 func (UnimplementedBlockchainServer) ProposeBlock(context.Context, *Block) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProposeBlock not implemented")
 }
-func (UnimplementedBlockchainServer) Vote(context.Context, *AVote) (*Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Vote not implemented")
-}
 func (UnimplementedBlockchainServer) GetBlock(context.Context, *BlockHeight) (*Block, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
 }
+func (UnimplementedBlockchainServer) GetBlockByHash(context.Context, *BlockHash) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockByHash not implemented")
+}
 func (UnimplementedBlockchainServer) GetLatestBlock(context.Context, *Empty) (*Block, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetLatestBlock not implemented")
 }
-func (UnimplementedBlockchainServer) CommitBlock(context.Context, *Empty) (*Empty, error) {
+func (UnimplementedBlockchainServer) CommitBlock(context.Context, *QuorumCertificate) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CommitBlock not implemented")
 }
+func (UnimplementedBlockchainServer) SendConsensusMessage(context.Context, *ConsensusMessage) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendConsensusMessage not implemented")
+}
+func (UnimplementedBlockchainServer) SendEvidence(context.Context, *Evidence) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendEvidence not implemented")
+}
+func (UnimplementedBlockchainServer) GetMempool(context.Context, *GetMempoolRequest) (*GetMempoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMempool not implemented")
+}
+func (UnimplementedBlockchainServer) SubscribeMempool(*Empty, grpc.ServerStreamingServer[MempoolEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeMempool not implemented")
+}
+func (UnimplementedBlockchainServer) GetAccountNonce(context.Context, *Account) (*AccountNonce, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountNonce not implemented")
+}
 func (UnimplementedBlockchainServer) StreamNodeInfo(*Empty, grpc.ServerStreamingServer[SteamNodeInfoResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamNodeInfo not implemented")
 }
@@ -209,38 +314,38 @@ func _Blockchain_SendTransaction_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Blockchain_ProposeBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Block)
+func _Blockchain_GossipTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionBatch)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BlockchainServer).ProposeBlock(ctx, in)
+		return srv.(BlockchainServer).GossipTransactions(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Blockchain_ProposeBlock_FullMethodName,
+		FullMethod: Blockchain_GossipTransactions_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BlockchainServer).ProposeBlock(ctx, req.(*Block))
+		return srv.(BlockchainServer).GossipTransactions(ctx, req.(*TransactionBatch))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Blockchain_Vote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AVote)
+func _Blockchain_ProposeBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Block)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BlockchainServer).Vote(ctx, in)
+		return srv.(BlockchainServer).ProposeBlock(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Blockchain_Vote_FullMethodName,
+		FullMethod: Blockchain_ProposeBlock_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BlockchainServer).Vote(ctx, req.(*AVote))
+		return srv.(BlockchainServer).ProposeBlock(ctx, req.(*Block))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -263,6 +368,24 @@ func _Blockchain_GetBlock_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Blockchain_GetBlockByHash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockHash)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetBlockByHash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetBlockByHash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetBlockByHash(ctx, req.(*BlockHash))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Blockchain_GetLatestBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
@@ -282,7 +405,7 @@ func _Blockchain_GetLatestBlock_Handler(srv interface{}, ctx context.Context, de
 }
 
 func _Blockchain_CommitBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(QuorumCertificate)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -294,7 +417,90 @@ func _Blockchain_CommitBlock_Handler(srv interface{}, ctx context.Context, dec f
 		FullMethod: Blockchain_CommitBlock_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BlockchainServer).CommitBlock(ctx, req.(*Empty))
+		return srv.(BlockchainServer).CommitBlock(ctx, req.(*QuorumCertificate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_SendConsensusMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsensusMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).SendConsensusMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_SendConsensusMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).SendConsensusMessage(ctx, req.(*ConsensusMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_SendEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Evidence)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).SendEvidence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_SendEvidence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).SendEvidence(ctx, req.(*Evidence))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_GetMempool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMempoolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetMempool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetMempool_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetMempool(ctx, req.(*GetMempoolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockchain_SubscribeMempool_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockchainServer).SubscribeMempool(m, &grpc.GenericServerStream[Empty, MempoolEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Blockchain_SubscribeMempoolServer = grpc.ServerStreamingServer[MempoolEvent]
+
+func _Blockchain_GetAccountNonce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Account)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockchainServer).GetAccountNonce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Blockchain_GetAccountNonce_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockchainServer).GetAccountNonce(ctx, req.(*Account))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -322,17 +528,21 @@ var Blockchain_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Blockchain_SendTransaction_Handler,
 		},
 		{
-			MethodName: "ProposeBlock",
-			Handler:    _Blockchain_ProposeBlock_Handler,
+			MethodName: "GossipTransactions",
+			Handler:    _Blockchain_GossipTransactions_Handler,
 		},
 		{
-			MethodName: "Vote",
-			Handler:    _Blockchain_Vote_Handler,
+			MethodName: "ProposeBlock",
+			Handler:    _Blockchain_ProposeBlock_Handler,
 		},
 		{
 			MethodName: "GetBlock",
 			Handler:    _Blockchain_GetBlock_Handler,
 		},
+		{
+			MethodName: "GetBlockByHash",
+			Handler:    _Blockchain_GetBlockByHash_Handler,
+		},
 		{
 			MethodName: "GetLatestBlock",
 			Handler:    _Blockchain_GetLatestBlock_Handler,
@@ -341,8 +551,29 @@ var Blockchain_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CommitBlock",
 			Handler:    _Blockchain_CommitBlock_Handler,
 		},
+		{
+			MethodName: "SendConsensusMessage",
+			Handler:    _Blockchain_SendConsensusMessage_Handler,
+		},
+		{
+			MethodName: "SendEvidence",
+			Handler:    _Blockchain_SendEvidence_Handler,
+		},
+		{
+			MethodName: "GetMempool",
+			Handler:    _Blockchain_GetMempool_Handler,
+		},
+		{
+			MethodName: "GetAccountNonce",
+			Handler:    _Blockchain_GetAccountNonce_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeMempool",
+			Handler:       _Blockchain_SubscribeMempool_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "StreamNodeInfo",
 			Handler:       _Blockchain_StreamNodeInfo_Handler,
@@ -1,9 +1,13 @@
 package p2p
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"go-blockchain-ber1/pkg/consensus"
 	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
 	"log/slog"
 	"sync"
 	"time"
@@ -19,16 +23,32 @@ type Peer struct {
 	conn   *grpc.ClientConn
 }
 
+// Transactions are gossiped in batches: a batch is sent when it is full or when the flush interval ticks
+const (
+	gossipBatchSize     = 100
+	gossipFlushInterval = 500 * time.Millisecond
+)
+
 type PeerManager struct {
+	peersMu       sync.RWMutex
 	peers         map[string]*Peer
-	leaderAddress string
+	peerAddresses map[string]string // validator id -> address
+
+	gossipMu    sync.Mutex
+	gossipQueue []*pb.Transaction
 }
 
-func NewPeerManager(leaderAddress string) *PeerManager {
+func NewPeerManager(validators []types.Validator) *PeerManager {
 	slog.Info("Init peer manager success")
+
+	peerAddresses := make(map[string]string)
+	for _, validator := range validators {
+		peerAddresses[validator.Id] = validator.Address
+	}
+
 	return &PeerManager{
 		peers:         make(map[string]*Peer),
-		leaderAddress: leaderAddress,
+		peerAddresses: peerAddresses,
 	}
 }
 
@@ -42,18 +62,59 @@ func (pm *PeerManager) AddPeer(address string) error {
 
 	client := pb.NewBlockchainClient(conn)
 
-	pm.peers[address] = &Peer{
+	peer := &Peer{
 		Address: address,
 
 		conn:   conn,
 		client: client,
 	}
 
-	slog.Debug(fmt.Sprintf("Added peer: %v", pm.peers[address]))
+	pm.peersMu.Lock()
+	pm.peers[address] = peer
+	pm.peersMu.Unlock()
+
+	slog.Debug(fmt.Sprintf("Added peer: %v", peer))
 
 	return nil
 }
 
+// getPeers returns the connected peers, peers can be added while they are used
+func (pm *PeerManager) getPeers() []*Peer {
+	pm.peersMu.RLock()
+	defer pm.peersMu.RUnlock()
+
+	peers := make([]*Peer, 0, len(pm.peers))
+	for _, peer := range pm.peers {
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
+
+// WatchValidators connects to the validators joining the set by staking, the set is checked every few seconds
+func (pm *PeerManager) WatchValidators(nodeId string, getValidators func() []types.Validator) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, validator := range getValidators() {
+			pm.peersMu.Lock()
+			pm.peerAddresses[validator.Id] = validator.Address
+			_, isConnected := pm.peers[validator.Address]
+			pm.peersMu.Unlock()
+
+			if validator.Id == nodeId || isConnected {
+				continue
+			}
+
+			slog.Info("Connect to new validator", "id", validator.Id, "address", validator.Address)
+			if err := pm.AddPeer(validator.Address); err != nil {
+				slog.Error("Cant add validator peer", "id", validator.Id, "err", err)
+			}
+		}
+	}
+}
+
 func (pm *PeerManager) AddPeers(addresses []string) {
 	for _, address := range addresses {
 		if err := pm.AddPeer(address); err != nil {
@@ -68,10 +129,10 @@ func (pm *PeerManager) BroastCastProposeBlock(block *pb.Block) {
 	defer cancel()
 	slog.Info("Trigger Broast cast Propose Block")
 
-	slog.Debug("Propose Block", "block", block, "peers", pm.peers)
+	slog.Debug("Propose Block", "block", block, "peers", pm.getPeers())
 
 	var wg sync.WaitGroup
-	for _, peer := range pm.peers {
+	for _, peer := range pm.getPeers() {
 		wg.Add(1)
 		go func(p *Peer) {
 			defer wg.Done()
@@ -84,16 +145,37 @@ func (pm *PeerManager) BroastCastProposeBlock(block *pb.Block) {
 	wg.Wait()
 }
 
-func (pm *PeerManager) BroastCastCommitBlock() {
+func (pm *PeerManager) BroastCastTransactions(txs []*pb.Transaction) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
+	slog.Debug("Gossip transactions", "count", len(txs))
+
+	batch := &pb.TransactionBatch{Transactions: txs}
+
 	var wg sync.WaitGroup
-	for _, peer := range pm.peers {
+	for _, peer := range pm.getPeers() {
+		wg.Add(1)
+		go func(p *Peer) {
+			defer wg.Done()
+			if _, err := p.client.GossipTransactions(ctx, batch); err != nil {
+				slog.Error("Gossip transactions failed to peer", "err", err, "peer", p.Address)
+			}
+		}(peer)
+	}
+	wg.Wait()
+}
+
+func (pm *PeerManager) BroastCastCommitBlock(cert *pb.QuorumCertificate) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, peer := range pm.getPeers() {
 		wg.Add(1)
 		go func(p *Peer) {
 			defer wg.Done()
-			if _, err := peer.client.CommitBlock(ctx, nil); err != nil {
+			if _, err := peer.client.CommitBlock(ctx, cert); err != nil {
 				slog.Error("Commit block failed to peer", "err", err, "peer", peer, "client", peer.client)
 			}
 		}(peer)
@@ -101,28 +183,115 @@ func (pm *PeerManager) BroastCastCommitBlock() {
 	wg.Wait()
 }
 
-// Leader
-func (pm *PeerManager) GetLeader() *Peer {
-	return pm.peers[pm.leaderAddress]
+func (pm *PeerManager) BroastCastConsensusMessages(messages []*pb.ConsensusMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, peer := range pm.getPeers() {
+		for _, msg := range messages {
+			wg.Add(1)
+			go func(p *Peer, msg *pb.ConsensusMessage) {
+				defer wg.Done()
+				if _, err := p.client.SendConsensusMessage(ctx, msg); err != nil {
+					slog.Warn("Consensus message failed to peer", "err", err, "peer", p.Address, "type", msg.Type)
+				}
+			}(peer, msg)
+		}
+	}
+	wg.Wait()
 }
 
-func (pm *PeerManager) GetBlockFromLeader(blockHeight uint64) (*pb.Block, error) {
+func (pm *PeerManager) BroastCastEvidence(evidence *pb.Evidence) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	block, err := pm.GetLeader().client.GetBlock(ctx, &pb.BlockHeight{Height: blockHeight})
-	if err != nil {
-		return nil, err
+	var wg sync.WaitGroup
+	for _, peer := range pm.getPeers() {
+		wg.Add(1)
+		go func(p *Peer) {
+			defer wg.Done()
+			if _, err := p.client.SendEvidence(ctx, evidence); err != nil {
+				slog.Error("Evidence failed to peer", "err", err, "peer", p.Address)
+			}
+		}(peer)
 	}
+	wg.Wait()
+}
 
-	return block, nil
+// getValidatorPeer returns the peer of the validator, nil if it is this node or its address is unknown
+func (pm *PeerManager) getValidatorPeer(validatorId string) *Peer {
+	pm.peersMu.RLock()
+	defer pm.peersMu.RUnlock()
+
+	return pm.peers[pm.peerAddresses[validatorId]]
+}
+
+// Sync
+// GetHighestPeer asks every peer for its latest block and returns the one with the highest block
+func (pm *PeerManager) GetHighestPeer() (*Peer, *pb.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var mu sync.Mutex
+	var highestPeer *Peer
+	var highestBlock *pb.Block
+
+	var wg sync.WaitGroup
+	for _, peer := range pm.getPeers() {
+		wg.Add(1)
+		go func(p *Peer) {
+			defer wg.Done()
+			block, err := p.client.GetLatestBlock(ctx, nil)
+			if err != nil {
+				slog.Debug("Cant get latest block from peer", "err", err, "peer", p.Address)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if highestBlock == nil || block.Height > highestBlock.Height {
+				highestPeer = p
+				highestBlock = block
+			}
+		}(peer)
+	}
+	wg.Wait()
+
+	if highestPeer == nil {
+		return nil, nil, fmt.Errorf("no peer available")
+	}
+
+	return highestPeer, highestBlock, nil
+}
+
+// FindCommonHeight walks down from the height until the block of the peer is the same as the local one,
+// the blocks above it are on another fork (PoW)
+func (pm *PeerManager) FindCommonHeight(peer *Peer, blockDB *storage.BlockDB, height uint64) (uint64, error) {
+	for ; height > 1; height-- {
+		localBlock, err := blockDB.GetBlock(height)
+		if err != nil {
+			return 0, err
+		}
+
+		peerBlock, err := pm.GetBlockFromPeer(peer, height)
+		if err != nil {
+			return 0, err
+		}
+
+		if bytes.Equal(localBlock.CurrentBlockHash, peerBlock.CurrentBlockHash) {
+			return height, nil
+		}
+	}
+
+	return height, nil
 }
 
-func (pm *PeerManager) GetLatestBlockFromLeader() (*pb.Block, error) {
+func (pm *PeerManager) GetBlockFromPeer(peer *Peer, blockHeight uint64) (*pb.Block, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	block, err := pm.GetLeader().client.GetLatestBlock(ctx, nil)
+	block, err := peer.client.GetBlock(ctx, &pb.BlockHeight{Height: blockHeight})
 	if err != nil {
 		return nil, err
 	}
@@ -130,24 +299,82 @@ func (pm *PeerManager) GetLatestBlockFromLeader() (*pb.Block, error) {
 	return block, nil
 }
 
-func (pm *PeerManager) SendTransactionToLeader(ctx context.Context, tx *pb.Transaction) error {
-	if _, err := pm.GetLeader().client.SendTransaction(ctx, tx); err != nil {
-		slog.Error("Cant not send transaction to leader", "err", err)
+func (pm *PeerManager) SendConsensusMessage(ctx context.Context, validatorId string, msg *pb.ConsensusMessage) error {
+	peer := pm.getValidatorPeer(validatorId)
+	if peer == nil {
+		return fmt.Errorf("peer of validator '%s' not found", validatorId)
+	}
+
+	if _, err := peer.client.SendConsensusMessage(ctx, msg); err != nil {
+		slog.Error("Cant send consensus message to validator", "err", err, "validator", validatorId, "type", msg.Type)
 		return err
 	}
 
-	slog.Info("Sent transaction to leader")
+	slog.Debug("Sent consensus message", "validator", validatorId, "type", msg.Type)
 
 	return nil
 }
 
-func (pm *PeerManager) SendVoteToLeader(ctx context.Context, vote *pb.AVote) error {
-	if _, err := pm.GetLeader().client.Vote(ctx, vote); err != nil {
-		slog.Error("Cant not send vote to leader", "err", err)
-		return err
+// SendConsensusOutput sends the messages, certificate, evidence and proposal produced by a consensus step
+func (pm *PeerManager) SendConsensusOutput(output *consensus.Output) {
+	if output == nil {
+		return
 	}
 
-	slog.Info("Sent vote to leader")
+	var broadcast []*pb.ConsensusMessage
+	for _, msg := range output.Messages {
+		if msg.To == "" {
+			broadcast = append(broadcast, msg.Envelope)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		pm.SendConsensusMessage(ctx, msg.To, msg.Envelope)
+		cancel()
+	}
+	if len(broadcast) > 0 {
+		pm.BroastCastConsensusMessages(broadcast)
+	}
+	if output.Certificate != nil {
+		pm.BroastCastCommitBlock(output.Certificate)
+	}
+	for _, evidence := range output.Evidence {
+		pm.BroastCastEvidence(evidence)
+	}
+	if output.Proposal != nil {
+		pm.BroastCastProposeBlock(output.Proposal)
+	}
+}
 
-	return nil
+// Gossip
+func (pm *PeerManager) GossipTransaction(tx *pb.Transaction) {
+	pm.gossipMu.Lock()
+	pm.gossipQueue = append(pm.gossipQueue, tx)
+	isBatchFull := len(pm.gossipQueue) >= gossipBatchSize
+	pm.gossipMu.Unlock()
+
+	if isBatchFull {
+		go pm.flushGossip()
+	}
+}
+
+func (pm *PeerManager) RunTransactionGossip() {
+	ticker := time.NewTicker(gossipFlushInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		pm.flushGossip()
+	}
+}
+
+func (pm *PeerManager) flushGossip() {
+	pm.gossipMu.Lock()
+	batch := pm.gossipQueue
+	pm.gossipQueue = nil
+	pm.gossipMu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	pm.BroastCastTransactions(batch)
 }
@@ -0,0 +1,156 @@
+package p2p
+
+import (
+	"context"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/storage"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// gossipRecorder is a peer that records the gossiped batches
+type gossipRecorder struct {
+	pb.UnimplementedBlockchainServer
+
+	mu      sync.Mutex
+	batches [][]*pb.Transaction
+}
+
+func (r *gossipRecorder) GossipTransactions(ctx context.Context, batch *pb.TransactionBatch) (*pb.Empty, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch.Transactions)
+	return &pb.Empty{}, nil
+}
+
+func (r *gossipRecorder) batchSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sizes := make([]int, len(r.batches))
+	for i, batch := range r.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+// newGossipPeer serves a recorder and returns a peer manager connected to it
+func newGossipPeer(t *testing.T) (*PeerManager, *gossipRecorder) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &gossipRecorder{}
+	server := grpc.NewServer()
+	pb.RegisterBlockchainServer(server, recorder)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	pm := NewPeerManager(nil)
+	if err := pm.AddPeer(listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	return pm, recorder
+}
+
+func signedTransaction(t *testing.T, amount float64) *pb.Transaction {
+	privateKey, err := wallet.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &blockchain.Transaction{Sender: wallet.PublicKeyToAddress(&privateKey.PublicKey), Receiver: []byte("bob"), Amount: amount, Nonce: 1}
+	wallet.SignTransaction(tx, privateKey)
+
+	pbTx := util.ConvertToPbTransaction(tx)
+	pbTx.PublicKey = []byte(util.EncodePublicKey(privateKey))
+	return pbTx
+}
+
+func TestGossipSendsFullBatchAtOnce(t *testing.T) {
+	pm, recorder := newGossipPeer(t)
+
+	for i := range gossipBatchSize {
+		pm.GossipTransaction(&pb.Transaction{Amount: float64(i)})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.batchSizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("full batch was not sent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if sizes := recorder.batchSizes(); len(sizes) != 1 || sizes[0] != gossipBatchSize {
+		t.Fatalf("batches %v, want one of %d", sizes, gossipBatchSize)
+	}
+}
+
+func TestGossipHoldsPartialBatchUntilFlush(t *testing.T) {
+	pm, recorder := newGossipPeer(t)
+
+	for i := range 3 {
+		pm.GossipTransaction(&pb.Transaction{Amount: float64(i)})
+	}
+	if sizes := recorder.batchSizes(); len(sizes) != 0 {
+		t.Fatalf("partial batch sent before flush: %v", sizes)
+	}
+
+	pm.flushGossip()
+	if sizes := recorder.batchSizes(); len(sizes) != 1 || sizes[0] != 3 {
+		t.Fatalf("batches %v, want one of 3", sizes)
+	}
+
+	// Nothing queued, nothing sent
+	pm.flushGossip()
+	if sizes := recorder.batchSizes(); len(sizes) != 1 {
+		t.Fatalf("empty flush sent a batch: %v", sizes)
+	}
+}
+
+func TestGossipTransactionsDropsDuplicates(t *testing.T) {
+	store := storage.NewMemoryStore()
+	blockDB := storage.NewBlockDB(store)
+	privateKey, err := wallet.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := &types.Genesis{
+		ChainId:    "test",
+		Validators: []types.Validator{{Id: "node1", PublicKey: util.EncodePublicKey(privateKey)}},
+	}
+	if err := blockDB.CreateGenesisBlock(genesis); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPeerManager(nil)
+	server := NewGRPCServer(blockDB, pm, blockchain.NewMemPool(storage.NewMemPoolDB(store)), nil, "node1")
+
+	first, second := signedTransaction(t, 1), signedTransaction(t, 2)
+	forged := signedTransaction(t, 3)
+	forged.Amount = 30
+
+	batch := &pb.TransactionBatch{Transactions: []*pb.Transaction{first, second, first, forged}}
+	for range 2 {
+		if _, err := server.GossipTransactions(context.Background(), batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := len(server.memPool.GetAllPendingTransactions()); count != 2 {
+		t.Fatalf("%d pending transactions, want 2", count)
+	}
+	// Only new transactions are passed on to the peers
+	if count := len(pm.gossipQueue); count != 2 {
+		t.Fatalf("%d transactions queued for gossip, want 2", count)
+	}
+}
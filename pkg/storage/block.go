@@ -1,41 +1,79 @@
 package storage
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
 	"go-blockchain-ber1/pkg/util"
 	"log/slog"
 	"strconv"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"google.golang.org/protobuf/proto"
 )
 
+// Height of the latest committed block
+const latestBlockHeightKey = "meta/latest_height"
+
+// Committed blocks: block height -> block
+const blockPrefix = "block/"
+
+// Index of committed blocks: block hash -> block height
+const blockHashPrefix = "hash/"
+
+// Index of committed transactions: tx hash -> block height
+const transactionPrefix = "tx/"
+
+// Highest committed nonce per sender: sender address -> nonce
+const noncePrefix = "nonce/"
+
+// Quorum certificate of each committed block: block height -> certificate
+const certificatePrefix = "qc/"
+
 type BlockDB struct {
-	DB *leveldb.DB
+	db Store
+
+	// Proof of stake, set from the genesis by Init
+	epochLength uint64
+	minStake    float64
 }
 
-func NewBlockDB(db *leveldb.DB) *BlockDB {
+func NewBlockDB(db Store) *BlockDB {
 	return &BlockDB{
-		DB: db,
+		db: db,
 	}
 }
 
-func (b *BlockDB) Init() {
+func (b *BlockDB) Init(genesis *types.Genesis) error {
 	slog.Info("Init BlockDB success")
 
-	b.CreateGenesisBlock()
-}
+	if err := checkSchemaVersion(b.db); err != nil {
+		return err
+	}
 
-func (b *BlockDB) CreateGenesisBlock() error {
-	iter := b.DB.NewIterator(nil, nil)
-	defer iter.Release()
+	if genesis.Pos != nil {
+		b.epochLength = genesis.Pos.EpochLength
+		b.minStake = genesis.Pos.MinStake
+	}
+
+	if err := b.CreateGenesisBlock(genesis); err != nil {
+		return fmt.Errorf("create genesis block: %w", err)
+	}
+
+	return b.checkConsistency()
+}
 
-	if err := iter.Error(); err != nil {
+func (b *BlockDB) CreateGenesisBlock(genesis *types.Genesis) error {
+	// Other namespaces (mempool, ...) share this database so only the block height tells if it is empty
+	hasBlock, err := b.db.Has([]byte(latestBlockHeightKey))
+	if err != nil {
 		return err
 	}
 
 	// If database is empty then create genesis block
-	if !iter.Next() {
+	if !hasBlock {
 		block := &blockchain.Block{
 			Transactions:      nil,
 			MerkleRootHash:    nil,
@@ -44,7 +82,19 @@ func (b *BlockDB) CreateGenesisBlock() error {
 		}
 		block.CurrentBlockHash = block.Hash()
 
-		b.SaveBlock(block)
+		// Balances, stakes and the validator set of epoch 0 are committed with the genesis block
+		state, err := b.genesisState(genesis)
+		if err != nil {
+			return err
+		}
+		batch := NewBatch()
+		batch.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(SchemaVersion)))
+		if err := b.writeEpoch(batch, 0, state); err != nil {
+			return err
+		}
+		if err := b.commitBlock(batch, block, nil, state); err != nil {
+			return err
+		}
 
 		slog.Info("Created genesis block success")
 	}
@@ -52,44 +102,337 @@ func (b *BlockDB) CreateGenesisBlock() error {
 	return nil
 }
 
-func (b *BlockDB) SaveBlock(block *blockchain.Block) error {
+// SaveBlock commits the block with its quorum certificate (nil with proof of work).
+// The block, the latest height, the indexes, the balances and stakes and the epoch snapshot are written in one batch:
+// a crash leaves the node at the previous block or at this one, never in between.
+func (b *BlockDB) SaveBlock(block *blockchain.Block, cert *pb.QuorumCertificate) error {
+	return b.commitBlock(NewBatch(), block, cert, b.NewState())
+}
+
+// commitBlock adds the block and its state changes on top of the state to the batch and writes it
+func (b *BlockDB) commitBlock(batch *Batch, block *blockchain.Block, cert *pb.QuorumCertificate, state *State) error {
 	slog.Debug("Save block", "block", *block)
-	// Write latest block height
-	blockHeight := strconv.Itoa(int(block.Height))
-	err := b.DB.Put([]byte("latest_block_height"), []byte(blockHeight), nil)
+	blockHeight := encodeHeight(block.Height)
+
+	// Index transactions, the highest nonce of each sender
+	nonces := make(map[string]uint64)
+	for _, tx := range block.Transactions {
+		batch.Put(transactionKey(tx.Hash()), blockHeight)
+		nonces[string(tx.Sender)] = max(nonces[string(tx.Sender)], tx.Nonce)
+	}
+	for sender, nonce := range nonces {
+		committedNonce, err := b.GetAccountNonce([]byte(sender))
+		if err != nil {
+			return err
+		}
+		if nonce > committedNonce {
+			batch.Put(nonceKey([]byte(sender)), []byte(strconv.FormatUint(nonce, 10)))
+		}
+	}
+
+	// Balances and stake table, evidence slashes after the transactions, then the end of the epoch
+	for _, tx := range block.Transactions {
+		if err := state.ApplyTransaction(tx); err != nil {
+			return err
+		}
+	}
+	for _, evidence := range block.Evidence {
+		if err := state.ApplyEvidence(evidence, block.Height); err != nil {
+			return err
+		}
+	}
+	if err := state.ApplyEpochEnd(block.Height); err != nil {
+		return err
+	}
+	state.write(batch)
+
+	// The last block of an epoch fixes the validator set of the next one
+	if b.isLastOfEpoch(block.Height) {
+		if err := b.writeEpoch(batch, block.Height/b.epochLength, state); err != nil {
+			return err
+		}
+	}
+
+	if cert != nil {
+		data, err := proto.Marshal(cert)
+		if err != nil {
+			return err
+		}
+		batch.Put(certificateKey(cert.Height), data)
+	}
+
+	data, err := encodeBlock(block)
+	if err != nil {
+		return err
+	}
+	batch.Put(blockKey(block.Height), data)
+	batch.Put(blockHashKey(block.CurrentBlockHash), blockHeight)
+	batch.Put([]byte(latestBlockHeightKey), blockHeight)
+
+	return b.db.Write(batch, true)
+}
+
+// checkConsistency verifies the latest height points at a stored block extending its parent.
+// Commits are atomic, a mismatch comes from a crash of a version writing them in several steps or a damaged disk.
+func (b *BlockDB) checkConsistency() error {
+	heightBytes, err := b.db.Get([]byte(latestBlockHeightKey))
 	if err != nil {
 		return err
 	}
+	latestHeight, err := decodeHeight(heightBytes)
+	if err != nil {
+		return err
+	}
+
+	block, err := b.GetBlock(latestHeight)
+	if err == ErrNotFound {
+		return fmt.Errorf("latest block height %d points at a missing block, remove the data directory to sync it again from peers", latestHeight)
+	}
+	if err != nil {
+		return err
+	}
+	if block.Height != latestHeight || !bytes.Equal(block.Hash(), block.CurrentBlockHash) {
+		return fmt.Errorf("latest block %d is damaged, remove the data directory to sync it again from peers", latestHeight)
+	}
+
+	if latestHeight > 1 {
+		parent, err := b.GetBlock(latestHeight - 1)
+		if err != nil {
+			return fmt.Errorf("parent of latest block %d: %w", latestHeight, err)
+		}
+		if !bytes.Equal(parent.CurrentBlockHash, block.PreviousBlockHash) {
+			return fmt.Errorf("latest block %d does not extend block %d, remove the data directory to sync it again from peers", latestHeight, latestHeight-1)
+		}
+	}
+
+	slog.Info("Checked database consistency", "latestHeight", latestHeight)
+
+	return nil
+}
+
+// RewindTo removes the blocks above the height (fork switch) and returns them, newest first.
+// Their transactions leave the index and are reverted from the balances and stakes,
+// the nonces of their senders are computed again from the remaining chain. Written in one synced batch as a commit.
+func (b *BlockDB) RewindTo(height uint64) ([]*blockchain.Block, error) {
+	latestHeight, err := b.GetlatestHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	batch := NewBatch()
+	state := b.NewState()
+	var removedBlocks []*blockchain.Block
+	senders := make(map[string]bool)
+	for blockHeight := uint64(latestHeight); blockHeight > height; blockHeight-- {
+		block, err := b.GetBlock(blockHeight)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := state.revertEpochEnd(blockHeight); err != nil {
+			return nil, err
+		}
+		for _, evidence := range block.Evidence {
+			if err := state.RevertEvidence(evidence, blockHeight); err != nil {
+				return nil, err
+			}
+		}
+		for i := len(block.Transactions) - 1; i >= 0; i-- {
+			tx := block.Transactions[i]
+			batch.Delete(transactionKey(tx.Hash()))
+			senders[string(tx.Sender)] = true
+
+			if err := state.RevertTransaction(tx); err != nil {
+				return nil, err
+			}
+		}
+		batch.Delete(blockKey(blockHeight))
+		batch.Delete(blockHashKey(block.CurrentBlockHash))
+		batch.Delete(certificateKey(blockHeight))
+		if b.isLastOfEpoch(blockHeight) {
+			batch.Delete(epochKey(blockHeight / b.epochLength))
+		}
+
+		removedBlocks = append(removedBlocks, block)
+	}
+
+	// Highest nonce of each sender in the remaining chain
+	nonces := make(map[string]uint64)
+	for blockHeight := uint64(1); blockHeight <= height && len(senders) > 0; blockHeight++ {
+		block, err := b.GetBlock(blockHeight)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range block.Transactions {
+			if senders[string(tx.Sender)] {
+				nonces[string(tx.Sender)] = max(nonces[string(tx.Sender)], tx.Nonce)
+			}
+		}
+	}
+	for sender := range senders {
+		if nonce, ok := nonces[sender]; ok {
+			batch.Put(nonceKey([]byte(sender)), []byte(strconv.FormatUint(nonce, 10)))
+		} else {
+			batch.Delete(nonceKey([]byte(sender)))
+		}
+	}
+
+	state.write(batch)
+	batch.Put([]byte(latestBlockHeightKey), encodeHeight(height))
+
+	return removedBlocks, b.db.Write(batch, true)
+}
+
+func blockKey(blockHeight uint64) []byte {
+	return heightKey(blockPrefix, blockHeight)
+}
+
+func blockHashKey(blockHash []byte) []byte {
+	return append([]byte(blockHashPrefix), blockHash...)
+}
+
+func transactionKey(txHash []byte) []byte {
+	return append([]byte(transactionPrefix), txHash...)
+}
+
+func (b *BlockDB) HasTransaction(txHash []byte) (bool, error) {
+	return b.db.Has(transactionKey(txHash))
+}
+
+func nonceKey(sender []byte) []byte {
+	return append([]byte(noncePrefix), sender...)
+}
+
+// GetAccountNonce returns the highest committed nonce of the sender, 0 if it never sent a transaction
+func (b *BlockDB) GetAccountNonce(sender []byte) (uint64, error) {
+	data, err := b.db.Get(nonceKey(sender))
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(string(data), 10, 64)
+}
+
+func certificateKey(blockHeight uint64) []byte {
+	return heightKey(certificatePrefix, blockHeight)
+}
+
+// GetCertificate returns the quorum certificate of the block, nil for the genesis block
+func (b *BlockDB) GetCertificate(blockHeight uint64) (*pb.QuorumCertificate, error) {
+	data, err := b.db.Get(certificateKey(blockHeight))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	data, _ := json.Marshal(block)
-	return b.DB.Put([]byte(blockHeight), data, nil)
+	var cert pb.QuorumCertificate
+	if err := proto.Unmarshal(data, &cert); err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
 }
 
 func (b *BlockDB) GetBlock(blockHeight uint64) (*blockchain.Block, error) {
-	heightStr := strconv.Itoa(int(blockHeight))
-	data, err := b.DB.Get([]byte(heightStr), nil)
+	return getBlock(b.db, blockHeight)
+}
+
+// GetBlockByHash returns the committed block with the hash, ErrNotFound when there is none
+func (b *BlockDB) GetBlockByHash(blockHash []byte) (*blockchain.Block, error) {
+	snapshot, err := b.db.Snapshot()
 	if err != nil {
 		return nil, err
 	}
+	defer snapshot.Release()
 
-	var block blockchain.Block
-	if err := json.Unmarshal(data, &block); err != nil {
+	heightBytes, err := snapshot.Get(blockHashKey(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	blockHeight, err := decodeHeight(heightBytes)
+	if err != nil {
 		return nil, err
 	}
 
-	return &block, nil
+	return getBlock(snapshot, blockHeight)
+}
+
+func getBlock(reader Reader, blockHeight uint64) (*blockchain.Block, error) {
+	data, err := reader.Get(blockKey(blockHeight))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBlock(data)
+}
+
+// Encoding of a stored block, first byte of the record.
+// Blocks written before it are JSON objects, starting with '{'.
+const (
+	BLOCK_ENCODING_JSON  byte = '{'
+	BLOCK_ENCODING_PROTO byte = 1 // pb.Block without certificate
+)
+
+// encodeBlock returns the record of the block, the protobuf message keeps hashes and signatures as raw bytes
+func encodeBlock(block *blockchain.Block) ([]byte, error) {
+	data, err := proto.Marshal(util.ConvertToPbBlock(block))
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{BLOCK_ENCODING_PROTO}, data...), nil
+}
+
+// decodeBlock reads a record of any encoding, legacy JSON blocks are not rewritten
+func decodeBlock(data []byte) (*blockchain.Block, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty block record")
+	}
+
+	switch data[0] {
+	case BLOCK_ENCODING_PROTO:
+		var pbBlock pb.Block
+		if err := proto.Unmarshal(data[1:], &pbBlock); err != nil {
+			return nil, err
+		}
+		return util.ConvertToBlockchainBlock(&pbBlock), nil
+	case BLOCK_ENCODING_JSON:
+		var block blockchain.Block
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	default:
+		return nil, fmt.Errorf("unknown block encoding %d", data[0])
+	}
 }
 
 func (b *BlockDB) GetLatestBlock() (*blockchain.Block, error) {
-	heighBytes, err := b.DB.Get([]byte("latest_block_height"), nil)
+	// Height and block are read from the same snapshot, a block saved in between does not mix them
+	snapshot, err := b.db.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	heighBytes, err := snapshot.Get([]byte(latestBlockHeightKey))
 	if err != nil {
 		slog.Error("GetLastestBlock Faild", "err", err)
 		return nil, err
 	}
 
-	heightInt := util.BytesToInt(heighBytes)
+	latestHeight, err := decodeHeight(heighBytes)
+	if err != nil {
+		return nil, err
+	}
 
-	block, err := b.GetBlock(uint64(heightInt))
+	block, err := getBlock(snapshot, latestHeight)
 	if err != nil {
 		slog.Error("GetLastestBlock Faild - GetBlock Faild", "err", err)
 		return nil, err
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"testing"
+)
+
+func testGenesis() *types.Genesis {
+	privateKey, _ := wallet.GenerateKeyPair()
+	return &types.Genesis{
+		ChainId: "test",
+		Validators: []types.Validator{
+			{Id: "node1", Address: "localhost:50051", PublicKey: util.EncodePublicKey(privateKey)},
+		},
+	}
+}
+
+// crashingStore fails its batch writes while crashed, as a node dying before the disk got them.
+// Writes outside a batch are counted: a commit must not do any.
+type crashingStore struct {
+	*MemoryStore
+
+	crashed bool
+	writes  int // Batches written
+	puts    int // Puts and deletes outside a batch
+}
+
+func (s *crashingStore) Write(batch *Batch, sync bool) error {
+	if s.crashed {
+		return errors.New("crashed")
+	}
+
+	s.writes++
+	return s.MemoryStore.Write(batch, sync)
+}
+
+func (s *crashingStore) Put(key []byte, value []byte) error {
+	s.puts++
+	return s.MemoryStore.Put(key, value)
+}
+
+func (s *crashingStore) Delete(key []byte) error {
+	s.puts++
+	return s.MemoryStore.Delete(key)
+}
+
+func TestSaveBlockIsAtomic(t *testing.T) {
+	genesis := testGenesis()
+	genesis.Balances = map[string]float64{"alice": 10}
+	store := &crashingStore{MemoryStore: NewMemoryStore()}
+	blockDB := NewBlockDB(store)
+	if err := blockDB.Init(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, _ := blockDB.GetLatestBlock()
+	tx := &blockchain.Transaction{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 4, Nonce: 1}
+	block := blockchain.NewBlock([]*blockchain.Transaction{tx}, latest, "node1", 0)
+
+	store.crashed = true
+	if err := blockDB.SaveBlock(block, nil); err == nil {
+		t.Fatal("block saved on a crashed store")
+	}
+	store.crashed = false
+
+	// Nothing of the block reached the store
+	if height, _ := blockDB.GetlatestHeight(); height != 1 {
+		t.Fatalf("latest height %d after a failed commit, want 1", height)
+	}
+	if _, err := blockDB.GetBlock(2); err != ErrNotFound {
+		t.Fatalf("block 2 after a failed commit: %v", err)
+	}
+	if found, _ := blockDB.HasTransaction(tx.Hash()); found {
+		t.Fatal("transaction indexed after a failed commit")
+	}
+	if nonce, _ := blockDB.GetAccountNonce(tx.Sender); nonce != 0 {
+		t.Fatalf("nonce %d after a failed commit", nonce)
+	}
+	if balance, _ := blockDB.GetBalance(tx.Sender); balance != 10 {
+		t.Fatalf("balance %v after a failed commit, want 10", balance)
+	}
+
+	writes := store.writes
+	if err := blockDB.SaveBlock(block, nil); err != nil {
+		t.Fatal(err)
+	}
+	if store.writes != writes+1 || store.puts != 0 {
+		t.Fatalf("block committed in %d batches and %d single writes, want one batch", store.writes-writes, store.puts)
+	}
+	if balance, _ := blockDB.GetBalance([]byte("bob")); balance != 4 {
+		t.Fatalf("balance of bob %v, want 4", balance)
+	}
+}
+
+func TestInitRejectsHalfWrittenStore(t *testing.T) {
+	damages := map[string]func(store *MemoryStore, block *blockchain.Block){
+		"height of a missing block": func(store *MemoryStore, block *blockchain.Block) {
+			store.Put([]byte(latestBlockHeightKey), encodeHeight(3))
+		},
+		"block of another chain": func(store *MemoryStore, block *blockchain.Block) {
+			block.PreviousBlockHash = []byte("another parent")
+			block.CurrentBlockHash = block.Hash()
+			data, _ := json.Marshal(block)
+			store.Put(blockKey(2), data)
+		},
+		"damaged block": func(store *MemoryStore, block *blockchain.Block) {
+			block.Timestamp++
+			data, _ := json.Marshal(block)
+			store.Put(blockKey(2), data)
+		},
+	}
+	for name, damage := range damages {
+		t.Run(name, func(t *testing.T) {
+			genesis := testGenesis()
+			store := NewMemoryStore()
+			blockDB := NewBlockDB(store)
+			if err := blockDB.Init(genesis); err != nil {
+				t.Fatal(err)
+			}
+			latest, _ := blockDB.GetLatestBlock()
+			block := blockchain.NewBlock(nil, latest, "node1", 0)
+			if err := blockDB.SaveBlock(block, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// A clean store opens again
+			if err := NewBlockDB(store).Init(genesis); err != nil {
+				t.Fatalf("consistent store rejected: %v", err)
+			}
+
+			damage(store, block)
+			if err := NewBlockDB(store).Init(genesis); err == nil {
+				t.Fatal("half-written store accepted")
+			}
+		})
+	}
+}
+
+func TestGetBlockByHash(t *testing.T) {
+	blockDB := NewBlockDB(NewMemoryStore())
+	if err := blockDB.Init(testGenesis()); err != nil {
+		t.Fatal(err)
+	}
+
+	var blocks []*blockchain.Block
+	for range 2 {
+		latest, _ := blockDB.GetLatestBlock()
+		block := blockchain.NewBlock(nil, latest, "node1", 0)
+		if err := blockDB.SaveBlock(block, nil); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	for _, block := range blocks {
+		found, err := blockDB.GetBlockByHash(block.CurrentBlockHash)
+		if err != nil {
+			t.Fatalf("block %d: %v", block.Height, err)
+		}
+		if found.Height != block.Height || !bytes.Equal(found.CurrentBlockHash, block.CurrentBlockHash) {
+			t.Fatalf("hash of block %d returned block %d", block.Height, found.Height)
+		}
+	}
+	if _, err := blockDB.GetBlockByHash([]byte("unknown hash")); err != ErrNotFound {
+		t.Fatalf("unknown hash: %v, want ErrNotFound", err)
+	}
+
+	// The rewound block leaves the index, the block replacing it at its height enters it
+	if _, err := blockDB.RewindTo(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blockDB.GetBlockByHash(blocks[1].CurrentBlockHash); err != ErrNotFound {
+		t.Fatalf("rewound block: %v, want ErrNotFound", err)
+	}
+	if _, err := blockDB.GetBlockByHash(blocks[0].CurrentBlockHash); err != nil {
+		t.Fatalf("block below the rewind: %v", err)
+	}
+
+	fork := blockchain.NewBlock(nil, blocks[0], "node2", 1)
+	if err := blockDB.SaveBlock(fork, nil); err != nil {
+		t.Fatal(err)
+	}
+	found, err := blockDB.GetBlockByHash(fork.CurrentBlockHash)
+	if err != nil || found.ProposerId != "node2" {
+		t.Fatalf("block of the fork: %v", err)
+	}
+}
+
+func randomBytes(size int) []byte {
+	data := make([]byte, size)
+	rand.Read(data)
+	return data
+}
+
+// testBlock is a signed block of transfers on top of a random parent, with a valid hash
+func testBlock(height uint64, txCount int) *blockchain.Block {
+	var transactions []*blockchain.Transaction
+	for i := range txCount {
+		transactions = append(transactions, &blockchain.Transaction{
+			Sender:    []byte("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"),
+			Receiver:  []byte("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"),
+			Amount:    1.5,
+			Timestamp: int64(1700000000000 + i),
+			Signature: randomBytes(64),
+			Fee:       0.01,
+			Nonce:     uint64(i + 1),
+			PublicKey: randomBytes(65),
+		})
+	}
+
+	parent := &blockchain.Block{CurrentBlockHash: randomBytes(32), Height: height - 1}
+	block := blockchain.NewBlock(transactions, parent, "node1", 2)
+	block.Timestamp = 1700000000
+	block.CurrentBlockHash = block.Hash()
+	block.ProposerSignature = randomBytes(64)
+
+	return block
+}
+
+func TestDecodeBlockKeepsHash(t *testing.T) {
+	withEvidence := testBlock(9, 3)
+	withEvidence.Evidence = []*pb.Evidence{{
+		Type:   "DOUBLE_VOTE",
+		NodeId: "node2",
+		Height: 8,
+		VoteA:  &pb.AVote{Approve: true, NodeId: "node2", BlockHeight: 8, BlockHash: randomBytes(32), Signature: randomBytes(64)},
+		VoteB:  &pb.AVote{Approve: true, NodeId: "node2", BlockHeight: 8, BlockHash: randomBytes(32), Signature: randomBytes(64)},
+	}}
+	withEvidence.CurrentBlockHash = withEvidence.Hash()
+
+	mined := testBlock(5, 1)
+	mined.ProposerId, mined.Round, mined.Difficulty, mined.Nonce = "", 0, 12, 4096
+	mined.CurrentBlockHash = mined.Hash()
+
+	blocks := map[string]*blockchain.Block{
+		"genesis":  {PreviousBlockHash: []byte("tran-tan-thanh"), Height: 1},
+		"empty":    testBlock(2, 0),
+		"100 txs":  testBlock(3, 100),
+		"evidence": withEvidence,
+		"pow":      mined,
+	}
+	blocks["genesis"].CurrentBlockHash = blocks["genesis"].Hash()
+
+	for name, block := range blocks {
+		t.Run(name, func(t *testing.T) {
+			data, err := encodeBlock(block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data[0] != BLOCK_ENCODING_PROTO {
+				t.Fatalf("encoding byte %d, want %d", data[0], BLOCK_ENCODING_PROTO)
+			}
+
+			decoded, err := decodeBlock(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// checkConsistency recomputes the hash of the stored block
+			if !bytes.Equal(decoded.Hash(), block.CurrentBlockHash) {
+				t.Fatal("hash of the decoded block differs")
+			}
+			if !bytes.Equal(decoded.ProposerSignature, block.ProposerSignature) {
+				t.Fatal("proposer signature lost")
+			}
+		})
+	}
+}
+
+func TestDecodeLegacyJSONBlock(t *testing.T) {
+	block := testBlock(4, 2)
+	data, _ := json.Marshal(block)
+
+	decoded, err := decodeBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Hash(), block.CurrentBlockHash) {
+		t.Fatal("hash of the legacy block differs")
+	}
+
+	if _, err := decodeBlock([]byte{7, 1, 2}); err == nil {
+		t.Fatal("unknown encoding decoded")
+	}
+}
+
+// Size and speed of the JSON records written before the protobuf encoding, against the current records.
+// go test ./pkg/storage -run ^$ -bench Block -benchmem
+func BenchmarkEncodeBlock(b *testing.B) {
+	for _, txCount := range []int{10, 100, 1000} {
+		block := testBlock(2, txCount)
+
+		b.Run(fmt.Sprintf("json/txs=%d", txCount), func(b *testing.B) {
+			var data []byte
+			for b.Loop() {
+				data, _ = json.Marshal(block)
+			}
+			b.ReportMetric(float64(len(data)), "bytes/block")
+		})
+		b.Run(fmt.Sprintf("proto/txs=%d", txCount), func(b *testing.B) {
+			var data []byte
+			for b.Loop() {
+				data, _ = encodeBlock(block)
+			}
+			b.ReportMetric(float64(len(data)), "bytes/block")
+		})
+	}
+}
+
+func BenchmarkDecodeBlock(b *testing.B) {
+	for _, txCount := range []int{10, 100, 1000} {
+		block := testBlock(2, txCount)
+		jsonData, _ := json.Marshal(block)
+		protoData, _ := encodeBlock(block)
+
+		b.Run(fmt.Sprintf("json/txs=%d", txCount), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := decodeBlock(jsonData); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("proto/txs=%d", txCount), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := decodeBlock(protoData); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
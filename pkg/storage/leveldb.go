@@ -1,19 +1,116 @@
 package storage
 
 import (
-	"log"
 	"log/slog"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	leveldbUtil "github.com/syndtr/goleveldb/leveldb/util"
 )
 
-func NewLevelDB(path string) *leveldb.DB {
+// LevelDBStore is the Store of a node, in the data directory
+type LevelDBStore struct {
+	db *leveldb.DB
+}
+
+func NewLevelDB(path string) (*LevelDBStore, error) {
 	db, err := leveldb.OpenFile(path, nil)
 	if err != nil {
-		log.Fatalf("LevelDB failed: %v", err)
+		return nil, err
 	}
 
 	slog.Info("Init LevelDB success")
 
-	return db
+	return &LevelDBStore{db: db}, nil
+}
+
+// get maps the not found error of the database and its snapshots to ErrNotFound
+func get(reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+}, key []byte) ([]byte, error) {
+	value, err := reader.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+
+	return value, err
+}
+
+func iterate(iter iterator.Iterator, fn func(key []byte, value []byte) error) error {
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+func (s *LevelDBStore) Get(key []byte) ([]byte, error) {
+	return get(s.db, key)
+}
+
+func (s *LevelDBStore) Has(key []byte) (bool, error) {
+	return s.db.Has(key, nil)
+}
+
+func (s *LevelDBStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return iterate(s.db.NewIterator(leveldbUtil.BytesPrefix(prefix), nil), fn)
+}
+
+func (s *LevelDBStore) Put(key []byte, value []byte) error {
+	return s.db.Put(key, value, nil)
+}
+
+func (s *LevelDBStore) Delete(key []byte) error {
+	return s.db.Delete(key, nil)
+}
+
+func (s *LevelDBStore) Write(batch *Batch, sync bool) error {
+	levelBatch := new(leveldb.Batch)
+	for _, op := range batch.ops {
+		if op.isDelete {
+			levelBatch.Delete(op.key)
+		} else {
+			levelBatch.Put(op.key, op.value)
+		}
+	}
+
+	return s.db.Write(levelBatch, &opt.WriteOptions{Sync: sync})
+}
+
+func (s *LevelDBStore) Snapshot() (Snapshot, error) {
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &levelDBSnapshot{snapshot: snapshot}, nil
+}
+
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+func (s *levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	return get(s.snapshot, key)
+}
+
+func (s *levelDBSnapshot) Has(key []byte) (bool, error) {
+	return s.snapshot.Has(key, nil)
+}
+
+func (s *levelDBSnapshot) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return iterate(s.snapshot.NewIterator(leveldbUtil.BytesPrefix(prefix), nil), fn)
+}
+
+func (s *levelDBSnapshot) Release() {
+	s.snapshot.Release()
 }
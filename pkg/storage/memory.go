@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps the keys in memory, to run nodes and consensus in-process without a data directory.
+// Every write is a new version of the store and a snapshot reads the versions up to its own, taking one copies nothing.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	data    map[string][]memoryValue // Versions of each key, oldest first
+	version uint64                   // Of the last write
+
+	snapshots map[uint64]int  // Open snapshots by version
+	stale     map[string]bool // Keys holding versions kept for a snapshot, pruned once no snapshot is open
+}
+
+// latestVersion reads the newest value of each key
+const latestVersion = ^uint64(0)
+
+type memoryValue struct {
+	version  uint64
+	value    []byte
+	isDelete bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data:      make(map[string][]memoryValue),
+		snapshots: make(map[uint64]int),
+		stale:     make(map[string]bool),
+	}
+}
+
+// valueAt returns the value of the key at the version, values are never modified in place
+func (s *MemoryStore) valueAt(key string, version uint64) ([]byte, bool) {
+	versions := s.data[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].version <= version {
+			return versions[i].value, !versions[i].isDelete
+		}
+	}
+
+	return nil, false
+}
+
+func (s *MemoryStore) get(key []byte, version uint64) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.valueAt(string(key), version)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return clone(value), nil
+}
+
+func (s *MemoryStore) has(key []byte, version uint64) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.valueAt(string(key), version)
+	return ok, nil
+}
+
+// iterate walks a copy of the matching keys at the version, fn can write to the store
+func (s *MemoryStore) iterate(prefix []byte, version uint64, fn func(key []byte, value []byte) error) error {
+	s.mu.RLock()
+	data := make(map[string][]byte)
+	for key := range s.data {
+		if !strings.HasPrefix(key, string(prefix)) {
+			continue
+		}
+		if value, ok := s.valueAt(key, version); ok {
+			data[key] = value
+		}
+	}
+	s.mu.RUnlock()
+
+	return iterateMap(data, fn)
+}
+
+func (s *MemoryStore) Get(key []byte) ([]byte, error) {
+	return s.get(key, latestVersion)
+}
+
+func (s *MemoryStore) Has(key []byte) (bool, error) {
+	return s.has(key, latestVersion)
+}
+
+func (s *MemoryStore) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return s.iterate(prefix, latestVersion, fn)
+}
+
+func (s *MemoryStore) Put(key []byte, value []byte) error {
+	batch := NewBatch()
+	batch.Put(key, value)
+	return s.Write(batch, false)
+}
+
+func (s *MemoryStore) Delete(key []byte) error {
+	batch := NewBatch()
+	batch.Delete(key)
+	return s.Write(batch, false)
+}
+
+// Write adds the writes of the batch as one new version
+func (s *MemoryStore) Write(batch *Batch, sync bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version++
+	for _, op := range batch.ops {
+		key := string(op.key)
+		s.data[key] = append(s.data[key], memoryValue{version: s.version, value: op.value, isDelete: op.isDelete})
+		s.prune(key)
+	}
+
+	return nil
+}
+
+// prune drops the versions of the key no open snapshot reads, must be called with the lock held
+func (s *MemoryStore) prune(key string) {
+	versions := s.data[key]
+
+	if len(s.snapshots) == 0 {
+		last := versions[len(versions)-1]
+		if last.isDelete {
+			delete(s.data, key)
+		} else {
+			s.data[key] = []memoryValue{last}
+		}
+		delete(s.stale, key)
+		return
+	}
+
+	// The oldest snapshot reads the newest version up to its own, older ones are not read anymore
+	oldest := s.version
+	for version := range s.snapshots {
+		oldest = min(oldest, version)
+	}
+	first := 0
+	for i, value := range versions {
+		if value.version <= oldest {
+			first = i
+		}
+	}
+	s.data[key] = versions[first:]
+	s.stale[key] = true
+}
+
+func (s *MemoryStore) Snapshot() (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[s.version]++
+
+	return &memorySnapshot{store: s, version: s.version}, nil
+}
+
+func (s *MemoryStore) release(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[version]--
+	if s.snapshots[version] > 0 {
+		return
+	}
+	delete(s.snapshots, version)
+
+	if len(s.snapshots) == 0 {
+		for key := range s.stale {
+			s.prune(key)
+		}
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// memorySnapshot reads the store at the version it was taken
+type memorySnapshot struct {
+	store    *MemoryStore
+	version  uint64
+	released sync.Once
+}
+
+func (s *memorySnapshot) Get(key []byte) ([]byte, error) {
+	return s.store.get(key, s.version)
+}
+
+func (s *memorySnapshot) Has(key []byte) (bool, error) {
+	return s.store.has(key, s.version)
+}
+
+func (s *memorySnapshot) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return s.store.iterate(prefix, s.version, fn)
+}
+
+func (s *memorySnapshot) Release() {
+	s.released.Do(func() {
+		s.store.release(s.version)
+	})
+}
+
+// iterateMap calls fn on the keys in byte order, like a LevelDB iterator
+func iterateMap(data map[string][]byte, fn func(key []byte, value []byte) error) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := fn([]byte(key), clone(data[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
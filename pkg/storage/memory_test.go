@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMemorySnapshotReadsItsVersion(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put([]byte("a"), []byte("1"))
+	store.Put([]byte("b"), []byte("1"))
+
+	snapshot, _ := store.Snapshot()
+	batch := NewBatch()
+	batch.Put([]byte("a"), []byte("2"))
+	batch.Delete([]byte("b"))
+	batch.Put([]byte("c"), []byte("2"))
+	store.Write(batch, false)
+
+	if value, _ := snapshot.Get([]byte("a")); string(value) != "1" {
+		t.Fatalf("snapshot reads a=%s, want 1", value)
+	}
+	if ok, _ := snapshot.Has([]byte("b")); !ok {
+		t.Fatal("key deleted after the snapshot is missing from it")
+	}
+	if _, err := snapshot.Get([]byte("c")); err != ErrNotFound {
+		t.Fatal("key added after the snapshot is in it")
+	}
+
+	var keys string
+	snapshot.Iterate(nil, func(key []byte, value []byte) error {
+		keys += fmt.Sprintf("%s=%s ", key, value)
+		return nil
+	})
+	if keys != "a=1 b=1 " {
+		t.Fatalf("snapshot iterates %q", keys)
+	}
+
+	if value, _ := store.Get([]byte("a")); string(value) != "2" {
+		t.Fatalf("store reads a=%s, want 2", value)
+	}
+	if ok, _ := store.Has([]byte("b")); ok {
+		t.Fatal("deleted key still in the store")
+	}
+
+	// Versions kept for the snapshot are dropped once it is released
+	snapshot.Release()
+	snapshot.Release()
+	if len(store.data) != 2 || len(store.data["a"]) != 1 {
+		t.Fatalf("%d keys and %d versions of a left after release", len(store.data), len(store.data["a"]))
+	}
+}
+
+// Taking a snapshot does not depend on the size of the store
+func BenchmarkMemorySnapshot(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		store := NewMemoryStore()
+		batch := NewBatch()
+		for i := range size {
+			batch.Put(heightKey(blockPrefix, uint64(i)), []byte("block"))
+		}
+		store.Write(batch, false)
+
+		b.Run(fmt.Sprintf("keys=%d", size), func(b *testing.B) {
+			for b.Loop() {
+				snapshot, _ := store.Snapshot()
+				snapshot.Get(heightKey(blockPrefix, 1))
+				snapshot.Release()
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/util"
+	"log/slog"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Pending transactions are stored under their own namespace so they survive a node restart
+const memPoolPrefix = "mempool/"
+
+type MemPoolDB struct {
+	db Store
+}
+
+func NewMemPoolDB(db Store) *MemPoolDB {
+	return &MemPoolDB{
+		db: db,
+	}
+}
+
+func memPoolKey(tx *pb.Transaction) []byte {
+	txHash := util.ConvertToBlockchainTransaction(tx).Hash()
+	return append([]byte(memPoolPrefix), txHash...)
+}
+
+func (m *MemPoolDB) SaveTransaction(tx *pb.Transaction) error {
+	data, err := proto.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	return m.db.Put(memPoolKey(tx), data)
+}
+
+func (m *MemPoolDB) DeleteTransactions(txs []*pb.Transaction) error {
+	batch := NewBatch()
+	for _, tx := range txs {
+		batch.Delete(memPoolKey(tx))
+	}
+
+	return m.db.Write(batch, false)
+}
+
+func (m *MemPoolDB) LoadTransactions() ([]*pb.Transaction, error) {
+	var txs []*pb.Transaction
+	err := m.db.Iterate([]byte(memPoolPrefix), func(key []byte, value []byte) error {
+		var tx pb.Transaction
+		if err := proto.Unmarshal(value, &tx); err != nil {
+			slog.Error("Skip broken pending transaction", "key", string(key), "err", err)
+			return nil
+		}
+		txs = append(txs, &tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Latest height of schema 1, the blocks are under their bare decimal height
+const legacyLatestBlockHeightKey = "latest_block_height"
+
+// MigrateSchema converts the database to the current key schema in one batch, the node must be stopped.
+// It returns the number of converted blocks, 0 when the database is empty or already up to date.
+func MigrateSchema(db Store) (int, error) {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if version == 0 || version == SchemaVersion {
+		return 0, nil
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("database uses key schema %d, newer than schema %d", version, SchemaVersion)
+	}
+
+	batch := NewBatch()
+	converted, err := migrateLegacyBlocks(db, batch)
+	if err != nil {
+		return 0, fmt.Errorf("migrate schema %d to %d: %w", version, SchemaVersion, err)
+	}
+	batch.Put([]byte(schemaVersionKey), []byte(strconv.Itoa(SchemaVersion)))
+
+	return converted, db.Write(batch, true)
+}
+
+// migrateLegacyBlocks moves the blocks of schema 1 to their namespace and builds what a commit writes with them:
+// the latest height, the hash, transaction and nonce indexes and the balances. Schema 1 had no staking, no evidence
+// and no certificates. Block records keep their JSON encoding, it is still read.
+func migrateLegacyBlocks(db Store, batch *Batch) (int, error) {
+	var latestHeight uint64
+	records := make(map[uint64][]byte)
+	err := db.Iterate(nil, func(key []byte, value []byte) error {
+		var err error
+		if string(key) == legacyLatestBlockHeightKey {
+			latestHeight, err = strconv.ParseUint(string(value), 10, 64)
+		} else {
+			var height uint64
+			height, err = strconv.ParseUint(string(key), 10, 64)
+			records[height] = value
+		}
+		if err != nil {
+			return fmt.Errorf("convert key %q: %w", key, err)
+		}
+
+		batch.Delete(key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Committed in height order: the nonces and balances come out as if the blocks were saved one by one
+	heights := make([]uint64, 0, len(records))
+	for height := range records {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+
+	state := NewBlockDB(db).NewState()
+	nonces := make(map[string]uint64)
+	for _, height := range heights {
+		block, err := decodeBlock(records[height])
+		if err != nil {
+			return 0, fmt.Errorf("block %d: %w", height, err)
+		}
+		if block.Height != height {
+			return 0, fmt.Errorf("block %d stored at height %d", block.Height, height)
+		}
+
+		blockHeight := encodeHeight(height)
+		batch.Put(blockKey(height), records[height])
+		batch.Put(blockHashKey(block.CurrentBlockHash), blockHeight)
+		for _, tx := range block.Transactions {
+			batch.Put(transactionKey(tx.Hash()), blockHeight)
+			nonces[string(tx.Sender)] = max(nonces[string(tx.Sender)], tx.Nonce)
+
+			if err := state.ApplyTransaction(tx); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for sender, nonce := range nonces {
+		if nonce > 0 {
+			batch.Put(nonceKey([]byte(sender)), []byte(strconv.FormatUint(nonce, 10)))
+		}
+	}
+	state.write(batch)
+	batch.Put([]byte(latestBlockHeightKey), encodeHeight(latestHeight))
+
+	return len(records), nil
+}
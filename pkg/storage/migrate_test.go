@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"encoding/json"
+	"go-blockchain-ber1/pkg/blockchain"
+	"strconv"
+	"testing"
+)
+
+// legacyStore writes a chain of the given height as schema 1 did: JSON blocks under their decimal height and latest_block_height
+func legacyStore(t *testing.T, latestHeight uint64) (*MemoryStore, []*blockchain.Block) {
+	store := NewMemoryStore()
+	genesis := &blockchain.Block{PreviousBlockHash: []byte("tran-tan-thanh"), Height: 1}
+	genesis.CurrentBlockHash = genesis.Hash()
+
+	blocks := []*blockchain.Block{genesis}
+	for height := uint64(2); height <= latestHeight; height++ {
+		var txs []*blockchain.Transaction
+		if height%2 == 0 {
+			txs = []*blockchain.Transaction{
+				{Sender: []byte("alice"), Receiver: []byte("bob"), Amount: 2, Timestamp: int64(height)},
+				{Sender: []byte("bob"), Receiver: []byte("carol"), Amount: 0.5, Timestamp: int64(height)},
+			}
+		}
+		latest := blocks[len(blocks)-1]
+		block := &blockchain.Block{Transactions: txs, PreviousBlockHash: latest.CurrentBlockHash, Height: height}
+		block.CurrentBlockHash = block.Hash()
+		blocks = append(blocks, block)
+	}
+
+	for _, block := range blocks {
+		data, _ := json.Marshal(block)
+		if err := store.Put([]byte(strconv.FormatUint(block.Height, 10)), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Put([]byte(legacyLatestBlockHeightKey), []byte(strconv.FormatUint(latestHeight, 10)))
+
+	return store, blocks
+}
+
+func TestMigrateLegacySchema(t *testing.T) {
+	// Past height 10 the decimal keys no longer iterate in height order
+	store, blocks := legacyStore(t, 12)
+	if version, _ := GetSchemaVersion(store); version != 1 {
+		t.Fatalf("legacy database has schema %d, want 1", version)
+	}
+	if err := NewBlockDB(store).Init(testGenesis()); err == nil {
+		t.Fatal("node started on a legacy database")
+	}
+
+	converted, err := MigrateSchema(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted != len(blocks) {
+		t.Fatalf("%d blocks converted, want %d", converted, len(blocks))
+	}
+
+	// Every record of the migrated store, and nothing else
+	want := map[string]string{
+		schemaVersionKey:     strconv.Itoa(SchemaVersion),
+		latestBlockHeightKey: string(encodeHeight(12)),
+		// 6 blocks with 2 transfers each
+		string(balanceKey([]byte("alice"))): "-12",
+		string(balanceKey([]byte("bob"))):   "9",
+		string(balanceKey([]byte("carol"))): "3",
+	}
+	for _, block := range blocks {
+		data, _ := json.Marshal(block)
+		want[string(blockKey(block.Height))] = string(data)
+		want[string(blockHashKey(block.CurrentBlockHash))] = string(encodeHeight(block.Height))
+		for _, tx := range block.Transactions {
+			want[string(transactionKey(tx.Hash()))] = string(encodeHeight(block.Height))
+		}
+	}
+
+	got := make(map[string]string)
+	store.Iterate(nil, func(key []byte, value []byte) error {
+		got[string(key)] = string(value)
+		return nil
+	})
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("record %q is %q, want %q", key, got[key], value)
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			t.Errorf("unexpected record %q", key)
+		}
+	}
+
+	// The node starts on it and reads the chain
+	blockDB := NewBlockDB(store)
+	if err := blockDB.Init(testGenesis()); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := blockDB.GetLatestBlock()
+	if err != nil || latest.Height != 12 {
+		t.Fatalf("latest block %v (%v), want height 12", latest, err)
+	}
+	block, err := blockDB.GetBlockByHash(blocks[9].CurrentBlockHash)
+	if err != nil || block.Height != 10 {
+		t.Fatalf("block by hash %v (%v), want height 10", block, err)
+	}
+
+	// Nothing left to migrate
+	if converted, err := MigrateSchema(store); err != nil || converted != 0 {
+		t.Fatalf("second migration converted %d blocks (%v)", converted, err)
+	}
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// Key schema of the database. Every key is in a namespace ending with '/', heights and sequence numbers
+// are 8 bytes big-endian so iterating a namespace walks them in numeric order.
+// Schema 1 (no version key) stored the blocks under their decimal height next to `latest_block_height`, `migrate-db` converts it.
+const SchemaVersion = 2
+
+const schemaVersionKey = "meta/schema_version"
+
+// encodeHeight is the big-endian form of a height (or a sequence number) in keys and values
+func encodeHeight(height uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, height)
+}
+
+func decodeHeight(data []byte) (uint64, error) {
+	if len(data) != 8 {
+		return 0, fmt.Errorf("invalid height of %d bytes", len(data))
+	}
+
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// heightKey is the key of a height in the namespace
+func heightKey(prefix string, height uint64) []byte {
+	return append([]byte(prefix), encodeHeight(height)...)
+}
+
+// GetSchemaVersion returns the key schema of the database: 0 when it is empty, 1 for a database written before the versioning
+func GetSchemaVersion(db Store) (int, error) {
+	data, err := db.Get([]byte(schemaVersionKey))
+	if err == ErrNotFound {
+		isLegacy, err := db.Has([]byte(legacyLatestBlockHeightKey))
+		if err != nil || !isLegacy {
+			return 0, err
+		}
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(data))
+}
+
+// checkSchemaVersion refuses a database this version can't read
+func checkSchemaVersion(db Store) error {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version == 0 || version == SchemaVersion:
+		return nil
+	case version < SchemaVersion:
+		return fmt.Errorf("database uses key schema %d, stop the node and run `migrate-db` to convert it to schema %d", version, SchemaVersion)
+	default:
+		return fmt.Errorf("database uses key schema %d, this node only reads schema %d", version, SchemaVersion)
+	}
+}
@@ -0,0 +1,553 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Available (unlocked) balance of each account: address -> balance
+const balancePrefix = "balance/"
+
+// Stake table: validator id -> stake
+const stakePrefix = "stake/"
+
+// Validator set of each epoch, taken from the stake table at the end of the previous epoch: epoch -> validators
+const epochPrefix = "epoch/"
+
+// Committed evidence of equivocation: height and validator id -> stake slashed
+const evidencePrefix = "evidence/"
+
+// Unstaked amounts paid back to their owner at the end of an epoch: epoch and validator id -> amount
+const releasedPrefix = "released/"
+
+// Validators accused by committed evidence: validator id -> height of the block committing the first evidence against it
+const accusedPrefix = "accused/"
+
+func balanceKey(address []byte) []byte {
+	return append([]byte(balancePrefix), address...)
+}
+
+func stakeKey(validatorId string) []byte {
+	return []byte(stakePrefix + validatorId)
+}
+
+func epochKey(epoch uint64) []byte {
+	return heightKey(epochPrefix, epoch)
+}
+
+func evidenceKey(nodeId string, height uint64) []byte {
+	return append(heightKey(evidencePrefix, height), nodeId...)
+}
+
+func releasedKey(validatorId string, epoch uint64) []byte {
+	return append(heightKey(releasedPrefix, epoch), validatorId...)
+}
+
+func accusedKey(validatorId string) []byte {
+	return []byte(accusedPrefix + validatorId)
+}
+
+// IsStakingEnabled tells if the genesis turns on proof of stake
+func (b *BlockDB) IsStakingEnabled() bool {
+	return b.epochLength > 0
+}
+
+// isLastOfEpoch tells if the block at height closes its epoch: it fixes the validator set of the next one
+func (b *BlockDB) isLastOfEpoch(height uint64) bool {
+	return b.IsStakingEnabled() && height%b.epochLength == 0
+}
+
+// Epoch returns the epoch of the block height, the genesis block is in epoch 0
+func (b *BlockDB) Epoch(height uint64) uint64 {
+	if b.epochLength == 0 || height == 0 {
+		return 0
+	}
+
+	return (height - 1) / b.epochLength
+}
+
+func (b *BlockDB) GetBalance(address []byte) (float64, error) {
+	data, err := b.db.Get(balanceKey(address))
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(string(data), 64)
+}
+
+// GetStake returns the stake table entry of the validator, nil when it never staked
+func (b *BlockDB) GetStake(validatorId string) (*types.Stake, error) {
+	data, err := b.db.Get(stakeKey(validatorId))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stake types.Stake
+	if err := json.Unmarshal(data, &stake); err != nil {
+		return nil, err
+	}
+
+	return &stake, nil
+}
+
+// HasEvidence tells if an evidence against the validator at this height is already committed, a validator is slashed once per height
+func (b *BlockDB) HasEvidence(nodeId string, height uint64) (bool, error) {
+	return b.db.Has(evidenceKey(nodeId, height))
+}
+
+// GetAccusedHeight returns the height of the block that committed the first evidence against the validator, 0 when none did
+func (b *BlockDB) GetAccusedHeight(validatorId string) (uint64, error) {
+	data, err := b.db.Get(accusedKey(validatorId))
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return decodeHeight(data)
+}
+
+// slash is the stake burned by a committed evidence, given back when its block is rolled back
+type slash struct {
+	Amount    float64 `json:"amount"`
+	Unbonding float64 `json:"unbonding,omitempty"`
+	Releasing float64 `json:"releasing,omitempty"`
+}
+
+// getSlash returns the stake slashed by a committed evidence
+func (b *BlockDB) getSlash(nodeId string, height uint64) (*slash, error) {
+	data, err := b.db.Get(evidenceKey(nodeId, height))
+	if err == ErrNotFound {
+		return &slash{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var slashed slash
+	if err := json.Unmarshal(data, &slashed); err != nil {
+		return nil, err
+	}
+
+	return &slashed, nil
+}
+
+// GetEpochValidators returns the validator set of the epoch, nil when the epoch is not reached yet
+func (b *BlockDB) GetEpochValidators(epoch uint64) ([]types.Validator, error) {
+	data, err := b.db.Get(epochKey(epoch))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var validators []types.Validator
+	if err := json.Unmarshal(data, &validators); err != nil {
+		return nil, err
+	}
+
+	return validators, nil
+}
+
+// genesisState holds the genesis balances and stakes the genesis validators with their own key as owner
+func (b *BlockDB) genesisState(genesis *types.Genesis) (*State, error) {
+	state := b.NewState()
+	for address, balance := range genesis.Balances {
+		state.balances[address] = balance
+	}
+
+	for _, validator := range genesis.Validators {
+		publicKey, err := util.DecodePublicKey(validator.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		stake := &types.Stake{
+			Validator: validator,
+			Owner:     string(wallet.PublicKeyToAddress(publicKey)),
+			Amount:    validator.Stake,
+		}
+		if stake.Amount == 0 {
+			stake.Amount = 1
+		}
+		stake.Validator.Stake = 0
+		state.stakes[validator.Id] = stake
+	}
+
+	return state, nil
+}
+
+// writeEpoch adds to the batch the validator set of the epoch, from the stake table with the changes of the state
+func (b *BlockDB) writeEpoch(batch *Batch, epoch uint64, state *State) error {
+	stakes, err := state.loadStakes()
+	if err != nil {
+		return err
+	}
+
+	validators := []types.Validator{}
+	for _, stake := range stakes {
+		if stake == nil || stake.Amount <= 0 || stake.Amount < b.minStake {
+			continue
+		}
+
+		validator := stake.Validator
+		validator.Stake = stake.Amount
+		validators = append(validators, validator)
+	}
+
+	// Without validators no block can be proposed or certified again: the previous set stays
+	if len(validators) == 0 {
+		if epoch == 0 {
+			return fmt.Errorf("no genesis validator has the minimum stake %v", b.minStake)
+		}
+
+		previous, err := b.db.Get(epochKey(epoch - 1))
+		if err != nil {
+			return fmt.Errorf("validator set of epoch %d: %w", epoch-1, err)
+		}
+		slog.Warn("No validator left in the stake table, keep the validator set of the previous epoch", "epoch", epoch, "minStake", b.minStake)
+		batch.Put(epochKey(epoch), previous)
+
+		return nil
+	}
+
+	// Same order on every node, it is the proposer order
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Id < validators[j].Id
+	})
+
+	data, _ := json.Marshal(validators)
+	batch.Put(epochKey(epoch), data)
+
+	return nil
+}
+
+// State is the account balances and the stake table with the transactions of pending blocks applied on top
+type State struct {
+	blockDB  *BlockDB
+	balances map[string]float64
+	stakes   map[string]*types.Stake
+	slashed  map[string]*slash   // evidence key -> stake slashed, nil when the evidence is reverted
+	released map[string]*float64 // released key -> amount paid back, nil when the release is reverted
+	accused  map[string]uint64   // validator id -> height of the block accusing it, 0 when the accusation is reverted
+}
+
+func (b *BlockDB) NewState() *State {
+	return &State{
+		blockDB:  b,
+		balances: make(map[string]float64),
+		stakes:   make(map[string]*types.Stake),
+		slashed:  make(map[string]*slash),
+		released: make(map[string]*float64),
+		accused:  make(map[string]uint64),
+	}
+}
+
+func (s *State) GetBalance(address []byte) (float64, error) {
+	if balance, ok := s.balances[string(address)]; ok {
+		return balance, nil
+	}
+
+	balance, err := s.blockDB.GetBalance(address)
+	if err != nil {
+		return 0, err
+	}
+	s.balances[string(address)] = balance
+
+	return balance, nil
+}
+
+// GetStake returns the stake of the validator, nil when it never staked
+func (s *State) GetStake(validatorId string) (*types.Stake, error) {
+	if stake, ok := s.stakes[validatorId]; ok {
+		return stake, nil
+	}
+
+	stake, err := s.blockDB.GetStake(validatorId)
+	if err != nil {
+		return nil, err
+	}
+	s.stakes[validatorId] = stake
+
+	return stake, nil
+}
+
+// loadStakes reads the whole stake table into the state and returns it, the changes of the state stay on top. Entries are nil for validators that never staked.
+func (s *State) loadStakes() (map[string]*types.Stake, error) {
+	err := s.blockDB.db.Iterate([]byte(stakePrefix), func(key []byte, value []byte) error {
+		validatorId := strings.TrimPrefix(string(key), stakePrefix)
+		if _, ok := s.stakes[validatorId]; ok {
+			return nil
+		}
+
+		var stake types.Stake
+		if err := json.Unmarshal(value, &stake); err != nil {
+			return err
+		}
+		s.stakes[validatorId] = &stake
+		return nil
+	})
+
+	return s.stakes, err
+}
+
+func (s *State) ApplyTransaction(tx *blockchain.Transaction) error {
+	return s.applyTransaction(tx, 1)
+}
+
+// RevertTransaction undoes the transaction, for blocks rolled back by a fork switch
+func (s *State) RevertTransaction(tx *blockchain.Transaction) error {
+	return s.applyTransaction(tx, -1)
+}
+
+// applyTransaction moves the amounts of the transaction, direction is -1 to undo it. Fees are burned.
+// An unstaked amount is not paid back at once, it starts unbonding (see ApplyEpochEnd).
+func (s *State) applyTransaction(tx *blockchain.Transaction, direction float64) error {
+	senderBalance, err := s.GetBalance(tx.Sender)
+	if err != nil {
+		return err
+	}
+
+	switch tx.Type {
+	case blockchain.TX_STAKE:
+		stake, err := s.GetStake(tx.ValidatorId)
+		if err != nil {
+			return err
+		}
+		if stake == nil {
+			stake = &types.Stake{
+				Validator: types.Validator{Id: tx.ValidatorId, PublicKey: string(tx.PublicKey)},
+				Owner:     string(tx.Sender),
+			}
+			s.stakes[tx.ValidatorId] = stake
+		}
+		if direction > 0 && tx.ValidatorAddress != "" {
+			stake.Validator.Address = tx.ValidatorAddress
+		}
+
+		stake.Amount += direction * tx.Amount
+		s.balances[string(tx.Sender)] = senderBalance - direction*(tx.Amount+tx.Fee)
+	case blockchain.TX_UNSTAKE:
+		stake, err := s.GetStake(tx.ValidatorId)
+		if err != nil {
+			return err
+		}
+		if stake != nil {
+			stake.Amount -= direction * tx.Amount
+			stake.Unbonding += direction * tx.Amount
+		}
+
+		s.balances[string(tx.Sender)] = senderBalance - direction*tx.Fee
+	default:
+		s.balances[string(tx.Sender)] = senderBalance - direction*(tx.Amount+tx.Fee)
+
+		receiverBalance, err := s.GetBalance(tx.Receiver)
+		if err != nil {
+			return err
+		}
+		s.balances[string(tx.Receiver)] = receiverBalance + direction*tx.Amount
+	}
+
+	return nil
+}
+
+// GetAccusedHeight returns the height of the block accusing the validator, 0 when it is not accused
+func (s *State) GetAccusedHeight(validatorId string) (uint64, error) {
+	if height, ok := s.accused[validatorId]; ok {
+		return height, nil
+	}
+
+	return s.blockDB.GetAccusedHeight(validatorId)
+}
+
+// ApplyEvidence slashes (burns) the whole stake of the equivocating validator with its unbonding amounts, it leaves the set at the next epoch.
+// Without proof of stake the validator is accused from the block at height, it leaves the genesis set.
+func (s *State) ApplyEvidence(evidence *pb.Evidence, height uint64) error {
+	stake, err := s.GetStake(evidence.NodeId)
+	if err != nil {
+		return err
+	}
+
+	accusedHeight, err := s.GetAccusedHeight(evidence.NodeId)
+	if err != nil {
+		return err
+	}
+	if accusedHeight == 0 {
+		s.accused[evidence.NodeId] = height
+	}
+
+	slashed := &slash{}
+	if stake != nil {
+		slashed = &slash{Amount: stake.Amount, Unbonding: stake.Unbonding, Releasing: stake.Releasing}
+		stake.Amount, stake.Unbonding, stake.Releasing = 0, 0, 0
+	}
+	s.slashed[string(evidenceKey(evidence.NodeId, evidence.Height))] = slashed
+
+	return nil
+}
+
+// RevertEvidence gives the slashed stake back and drops the accusation of the block at height, for blocks rolled back by a fork switch
+func (s *State) RevertEvidence(evidence *pb.Evidence, height uint64) error {
+	slashed, err := s.blockDB.getSlash(evidence.NodeId, evidence.Height)
+	if err != nil {
+		return err
+	}
+
+	accusedHeight, err := s.GetAccusedHeight(evidence.NodeId)
+	if err != nil {
+		return err
+	}
+	if accusedHeight == height {
+		s.accused[evidence.NodeId] = 0
+	}
+
+	stake, err := s.GetStake(evidence.NodeId)
+	if err != nil {
+		return err
+	}
+	if stake != nil {
+		stake.Amount += slashed.Amount
+		stake.Unbonding += slashed.Unbonding
+		stake.Releasing += slashed.Releasing
+	}
+	s.slashed[string(evidenceKey(evidence.NodeId, evidence.Height))] = nil
+
+	return nil
+}
+
+// ApplyEpochEnd runs after the evidence of the last block of an epoch: the amounts unstaked during the previous epoch go back to their owner,
+// the amounts unstaked during this one wait for the end of the next epoch. Evidence can slash them until then.
+func (s *State) ApplyEpochEnd(height uint64) error {
+	if !s.blockDB.isLastOfEpoch(height) {
+		return nil
+	}
+	epoch := s.blockDB.Epoch(height)
+
+	stakes, err := s.loadStakes()
+	if err != nil {
+		return err
+	}
+
+	for validatorId, stake := range stakes {
+		if stake == nil {
+			continue
+		}
+
+		if stake.Releasing > 0 {
+			balance, err := s.GetBalance([]byte(stake.Owner))
+			if err != nil {
+				return err
+			}
+			s.balances[stake.Owner] = balance + stake.Releasing
+
+			released := stake.Releasing
+			s.released[string(releasedKey(validatorId, epoch))] = &released
+		}
+		stake.Releasing, stake.Unbonding = stake.Unbonding, 0
+	}
+
+	return nil
+}
+
+// revertEpochEnd undoes ApplyEpochEnd, for blocks rolled back by a fork switch
+func (s *State) revertEpochEnd(height uint64) error {
+	if !s.blockDB.isLastOfEpoch(height) {
+		return nil
+	}
+	epoch := s.blockDB.Epoch(height)
+
+	prefix := heightKey(releasedPrefix, epoch)
+	released := make(map[string]float64)
+	err := s.blockDB.db.Iterate(prefix, func(key []byte, value []byte) error {
+		amount, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return err
+		}
+		released[string(key[len(prefix):])] = amount
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	stakes, err := s.loadStakes()
+	if err != nil {
+		return err
+	}
+
+	for validatorId, stake := range stakes {
+		if stake == nil {
+			continue
+		}
+
+		amount := released[validatorId]
+		if amount > 0 {
+			balance, err := s.GetBalance([]byte(stake.Owner))
+			if err != nil {
+				return err
+			}
+			s.balances[stake.Owner] = balance - amount
+			s.released[string(releasedKey(validatorId, epoch))] = nil
+		}
+		stake.Unbonding, stake.Releasing = stake.Releasing, amount
+	}
+
+	return nil
+}
+
+// write adds the changed balances, stakes, slashes and releases to the batch
+func (s *State) write(batch *Batch) {
+	for address, balance := range s.balances {
+		batch.Put(balanceKey([]byte(address)), []byte(strconv.FormatFloat(balance, 'f', -1, 64)))
+	}
+
+	for validatorId, stake := range s.stakes {
+		if stake == nil {
+			continue
+		}
+		data, _ := json.Marshal(stake)
+		batch.Put(stakeKey(validatorId), data)
+	}
+
+	for key, slashed := range s.slashed {
+		if slashed == nil {
+			batch.Delete([]byte(key))
+			continue
+		}
+		data, _ := json.Marshal(slashed)
+		batch.Put([]byte(key), data)
+	}
+
+	for key, amount := range s.released {
+		if amount == nil {
+			batch.Delete([]byte(key))
+			continue
+		}
+		batch.Put([]byte(key), []byte(strconv.FormatFloat(*amount, 'f', -1, 64)))
+	}
+
+	for validatorId, height := range s.accused {
+		if height == 0 {
+			batch.Delete(accusedKey(validatorId))
+			continue
+		}
+		batch.Put(accusedKey(validatorId), encodeHeight(height))
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"go-blockchain-ber1/pkg/blockchain"
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"go-blockchain-ber1/pkg/types"
+	"go-blockchain-ber1/pkg/util"
+	"go-blockchain-ber1/pkg/wallet"
+	"testing"
+)
+
+func TestEpochWithoutValidatorsKeepsPreviousSet(t *testing.T) {
+	genesis := testGenesis()
+	genesis.Pos = &types.PosConfig{EpochLength: 2, MinStake: 1}
+	blockDB := NewBlockDB(NewMemoryStore())
+	if err := blockDB.Init(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	// The only validator is slashed in the last block of epoch 0
+	latest, _ := blockDB.GetLatestBlock()
+	block := blockchain.NewBlock(nil, latest, "node1", 0)
+	block.Evidence = []*pb.Evidence{{Type: "DOUBLE_VOTE", NodeId: "node1", Height: 1}}
+	if err := blockDB.SaveBlock(block, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stake, _ := blockDB.GetStake("node1")
+	if stake.Amount != 0 {
+		t.Fatalf("stake %v after slashing", stake.Amount)
+	}
+	validators, err := blockDB.GetEpochValidators(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validators) != 1 || validators[0].Id != "node1" {
+		t.Fatalf("validator set of epoch 1 is %v, want the set of epoch 0", validators)
+	}
+}
+
+func TestGenesisWithoutValidatorsAboveMinStake(t *testing.T) {
+	genesis := testGenesis()
+	genesis.Pos = &types.PosConfig{EpochLength: 2, MinStake: 10}
+
+	if err := NewBlockDB(NewMemoryStore()).Init(genesis); err == nil {
+		t.Fatal("genesis with an empty validator set accepted")
+	}
+}
+
+func TestEvidenceAccusesValidatorFromItsBlock(t *testing.T) {
+	store := NewMemoryStore()
+	blockDB := NewBlockDB(store)
+	if err := blockDB.Init(testGenesis()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Evidence against node2 in blocks 2 and 3, the first one accuses it
+	for height := uint64(2); height <= 3; height++ {
+		latest, _ := blockDB.GetLatestBlock()
+		block := blockchain.NewBlock(nil, latest, "node1", 0)
+		block.Evidence = []*pb.Evidence{{Type: "DOUBLE_VOTE", NodeId: "node2", Height: height}}
+		if err := blockDB.SaveBlock(block, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if height, _ := blockDB.GetAccusedHeight("node2"); height != 2 {
+		t.Fatalf("node2 accused at height %d, want 2", height)
+	}
+
+	// Rolling back block 3 keeps the accusation of block 2, rolling back block 2 drops it
+	if _, err := blockDB.RewindTo(2); err != nil {
+		t.Fatal(err)
+	}
+	if height, _ := blockDB.GetAccusedHeight("node2"); height != 2 {
+		t.Fatalf("node2 accused at height %d after rewinding block 3, want 2", height)
+	}
+	if _, err := blockDB.RewindTo(1); err != nil {
+		t.Fatal(err)
+	}
+	if height, _ := blockDB.GetAccusedHeight("node2"); height != 0 {
+		t.Fatalf("node2 still accused at height %d after rewinding its block", height)
+	}
+}
+
+// newUnstakeTest commits blocks 2 and 3 of a chain of 2-block epochs, node2 unstakes 3 of its stake of 5 in block 3 (epoch 1)
+func newUnstakeTest(t *testing.T) (*BlockDB, []byte) {
+	privateKey, _ := wallet.GenerateKeyPair()
+	genesis := testGenesis()
+	genesis.Pos = &types.PosConfig{EpochLength: 2, MinStake: 1}
+	genesis.Validators = append(genesis.Validators, types.Validator{Id: "node2", PublicKey: util.EncodePublicKey(privateKey), Stake: 5})
+	blockDB := NewBlockDB(NewMemoryStore())
+	if err := blockDB.Init(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := wallet.PublicKeyToAddress(&privateKey.PublicKey)
+	unstake := &blockchain.Transaction{Type: blockchain.TX_UNSTAKE, Sender: owner, ValidatorId: "node2", Amount: 3, Nonce: 1}
+	saveTestBlock(t, blockDB, nil, nil)
+	saveTestBlock(t, blockDB, []*blockchain.Transaction{unstake}, nil)
+
+	return blockDB, owner
+}
+
+func saveTestBlock(t *testing.T, blockDB *BlockDB, txs []*blockchain.Transaction, evidence []*pb.Evidence) {
+	latest, _ := blockDB.GetLatestBlock()
+	block := blockchain.NewBlock(txs, latest, "node1", 0)
+	block.Evidence = evidence
+	if err := blockDB.SaveBlock(block, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkUnstaked(t *testing.T, blockDB *BlockDB, owner []byte, amount, unbonding, releasing, balance float64) {
+	t.Helper()
+	stake, _ := blockDB.GetStake("node2")
+	if stake.Amount != amount || stake.Unbonding != unbonding || stake.Releasing != releasing {
+		t.Fatalf("stake %v unbonding %v releasing %v, want %v %v %v", stake.Amount, stake.Unbonding, stake.Releasing, amount, unbonding, releasing)
+	}
+	if got, _ := blockDB.GetBalance(owner); got != balance {
+		t.Fatalf("balance %v, want %v", got, balance)
+	}
+}
+
+func TestUnstakedFundsReleasedAtEndOfNextEpoch(t *testing.T) {
+	blockDB, owner := newUnstakeTest(t)
+	checkUnstaked(t, blockDB, owner, 2, 3, 0, 0)
+
+	// Out of the set of epoch 2, still held during it
+	saveTestBlock(t, blockDB, nil, nil)
+	checkUnstaked(t, blockDB, owner, 2, 0, 3, 0)
+	validators, _ := blockDB.GetEpochValidators(2)
+	if len(validators) != 2 || validators[1].Stake != 2 {
+		t.Fatalf("validator set of epoch 2 is %v, want node2 with a stake of 2", validators)
+	}
+	saveTestBlock(t, blockDB, nil, nil)
+	checkUnstaked(t, blockDB, owner, 2, 0, 3, 0)
+
+	// Paid back by the last block of epoch 2
+	saveTestBlock(t, blockDB, nil, nil)
+	checkUnstaked(t, blockDB, owner, 2, 0, 0, 3)
+
+	// Rolling the blocks back holds the funds again, then gives the stake back
+	if _, err := blockDB.RewindTo(5); err != nil {
+		t.Fatal(err)
+	}
+	checkUnstaked(t, blockDB, owner, 2, 0, 3, 0)
+	if _, err := blockDB.RewindTo(1); err != nil {
+		t.Fatal(err)
+	}
+	checkUnstaked(t, blockDB, owner, 5, 0, 0, 0)
+}
+
+func TestEvidenceAfterUnstakeSlashesUnstakedFunds(t *testing.T) {
+	blockDB, owner := newUnstakeTest(t)
+
+	// Evidence of epoch 1 committed in epoch 2, after the unstake
+	saveTestBlock(t, blockDB, nil, nil)
+	saveTestBlock(t, blockDB, nil, []*pb.Evidence{{Type: "DOUBLE_VOTE", NodeId: "node2", Height: 3}})
+	checkUnstaked(t, blockDB, owner, 0, 0, 0, 0)
+
+	// Nothing left to pay back
+	saveTestBlock(t, blockDB, nil, nil)
+	checkUnstaked(t, blockDB, owner, 0, 0, 0, 0)
+
+	// Rolling the evidence back gives the stake and the unstaked funds back
+	if _, err := blockDB.RewindTo(4); err != nil {
+		t.Fatal(err)
+	}
+	checkUnstaked(t, blockDB, owner, 2, 0, 3, 0)
+}
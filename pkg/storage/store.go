@@ -0,0 +1,67 @@
+package storage
+
+import "errors"
+
+// ErrNotFound is returned by Get when the key is missing, whatever the backend
+var ErrNotFound = errors.New("storage: not found")
+
+// Reader reads a key-value store. Iterate walks the keys with the prefix in byte order,
+// key and value are only valid during the call; an error of fn stops the walk and is returned.
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	Iterate(prefix []byte, fn func(key []byte, value []byte) error) error
+}
+
+// Store is the key-value backend of BlockDB, MemPoolDB and WalDB: LevelDB on a node, in memory to run in-process
+type Store interface {
+	Reader
+
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+
+	// Write applies every write of the batch at once, sync waits for the disk
+	Write(batch *Batch, sync bool) error
+
+	// Snapshot is a consistent view of the store, it must be released
+	Snapshot() (Snapshot, error)
+
+	Close() error
+}
+
+type Snapshot interface {
+	Reader
+
+	Release()
+}
+
+type batchOp struct {
+	key      []byte
+	value    []byte
+	isDelete bool
+}
+
+// Batch records writes to apply together with Store.Write, in order
+type Batch struct {
+	ops []batchOp
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+func (b *Batch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, batchOp{key: clone(key), value: clone(value)})
+}
+
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: clone(key), isDelete: true})
+}
+
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func clone(data []byte) []byte {
+	return append([]byte{}, data...)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"go-blockchain-ber1/pkg/p2p/pb"
+	"log/slog"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Consensus write-ahead log: sequence number -> entry
+const walPrefix = "wal/"
+
+type WalDB struct {
+	db Store
+
+	mu      sync.Mutex
+	nextSeq uint64
+}
+
+func NewWalDB(db Store) *WalDB {
+	w := &WalDB{
+		db: db,
+	}
+
+	// Appends go after the last entry left before the restart
+	db.Iterate([]byte(walPrefix), func(key []byte, value []byte) error {
+		if seq, err := decodeHeight(key[len(walPrefix):]); err == nil {
+			w.nextSeq = max(w.nextSeq, seq+1)
+		}
+		return nil
+	})
+
+	return w
+}
+
+func walKey(seq uint64) []byte {
+	return heightKey(walPrefix, seq)
+}
+
+// Append writes the entry to disk before the node acts on it (sends a vote, broadcasts a proposal)
+func (w *WalDB) Append(entry *pb.WalEntry) error {
+	data, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	batch := NewBatch()
+	batch.Put(walKey(w.nextSeq), data)
+	if err := w.db.Write(batch, true); err != nil {
+		return err
+	}
+	w.nextSeq++
+
+	return nil
+}
+
+// Load returns the entries in append order
+func (w *WalDB) Load() ([]*pb.WalEntry, error) {
+	var entries []*pb.WalEntry
+	err := w.db.Iterate([]byte(walPrefix), func(key []byte, value []byte) error {
+		var entry pb.WalEntry
+		if err := proto.Unmarshal(value, &entry); err != nil {
+			slog.Error("Skip broken consensus WAL entry", "key", string(key), "err", err)
+			return nil
+		}
+		entries = append(entries, &entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Prune deletes the entries of the committed heights
+func (w *WalDB) Prune(committedHeight uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	batch := NewBatch()
+	err := w.db.Iterate([]byte(walPrefix), func(key []byte, value []byte) error {
+		var entry pb.WalEntry
+		if err := proto.Unmarshal(value, &entry); err != nil || entry.Height <= committedHeight {
+			batch.Delete(key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	return w.db.Write(batch, false)
+}
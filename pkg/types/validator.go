@@ -0,0 +1,59 @@
+package types
+
+type Validator struct {
+	Id        string  `json:"id"`
+	Address   string  `json:"address"`
+	PublicKey string  `json:"publicKey"`
+	Stake     float64 `json:"stake,omitempty"` // Voting power, 1 when not set
+}
+
+type ValidatorKey struct {
+	Id         string `json:"id"`
+	PrivateKey string `json:"privateKey"`
+}
+
+type Genesis struct {
+	ChainId         string                 `json:"chainId"`
+	ConsensusEngine string                 `json:"consensusEngine,omitempty"` // vote (default), pbft or pow
+	Pow             *PowConfig             `json:"pow,omitempty"`
+	Pos             *PosConfig             `json:"pos,omitempty"`
+	BlockProduction *BlockProductionConfig `json:"blockProduction,omitempty"`
+	Validators      []Validator            `json:"validators"`
+
+	// Initial balance of each address, only enforced with proof of stake
+	Balances map[string]float64 `json:"balances,omitempty"`
+}
+
+// PowConfig tunes the proof of work engine, zero values take the defaults
+type PowConfig struct {
+	InitialDifficulty uint32 `json:"initialDifficulty,omitempty"` // Leading zero bits of the block hash
+	MinDifficulty     uint32 `json:"minDifficulty,omitempty"`
+	TargetBlockTime   int64  `json:"targetBlockTime,omitempty"`  // Seconds
+	RetargetInterval  uint64 `json:"retargetInterval,omitempty"` // Blocks between difficulty adjustments
+}
+
+// BlockProductionConfig tunes when the proposer (or miner) produces a block, zero values take the defaults
+type BlockProductionConfig struct {
+	BlockInterval   int64  `json:"blockInterval,omitempty"`   // Seconds between two blocks
+	MaxTransactions int    `json:"maxTransactions,omitempty"` // Produce a block as soon as the mempool holds this many transactions, 0 to wait for the interval
+	MaxBytes        int    `json:"maxBytes,omitempty"`        // Produce a block as soon as the pending transactions take this many bytes, 0 to wait for the interval
+	EmptyBlocks     bool   `json:"emptyBlocks,omitempty"`     // Produce an empty block at each interval when the mempool is empty, so followers see the chain is alive
+	PipelineDepth   uint64 `json:"pipelineDepth,omitempty"`   // Heights in flight at once: the proposer of N+1 proposes on top of N while N is being committed. 1 (default) turns pipelining off, vote engine only
+}
+
+// PosConfig turns on proof of stake: the validator set of each epoch comes from the stake table
+type PosConfig struct {
+	EpochLength uint64  `json:"epochLength"`        // Blocks per epoch, validator set changes take effect at epoch boundaries
+	MinStake    float64 `json:"minStake,omitempty"` // Stake needed to be in the validator set
+}
+
+// Stake is an entry of the stake table: the validator and the balance locked by its owner
+type Stake struct {
+	Validator Validator `json:"validator"`
+	Owner     string    `json:"owner"` // Address that staked, only it can unstake
+	Amount    float64   `json:"amount"`
+
+	// Unstaked amounts stay slashable until paid back to the owner at the end of the epoch after the unstake
+	Unbonding float64 `json:"unbonding,omitempty"` // Unstaked during the current epoch
+	Releasing float64 `json:"releasing,omitempty"` // Unstaked during the previous epoch, paid back at the end of the current one
+}
@@ -56,7 +56,7 @@ func Base58CheckEncode(payload []byte) string {
 	return Base58Encode(full)
 }
 
-func base58Decode(input string) ([]byte, error) {
+func Base58Decode(input string) ([]byte, error) {
 	result := big.NewInt(0)
 	base := big.NewInt(58)
 
@@ -85,7 +85,7 @@ func base58Decode(input string) ([]byte, error) {
 }
 
 func Base58CheckDecode(input string) ([]byte, error) {
-	full, err := base58Decode(input)
+	full, err := Base58Decode(input)
 	if err != nil {
 		return nil, err
 	}
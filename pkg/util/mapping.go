@@ -6,23 +6,11 @@ import (
 )
 
 func ConvertToPbTransaction(tx *blockchain.Transaction) *pb.Transaction {
-	return &pb.Transaction{
-		Sender:    tx.Sender,
-		Receiver:  tx.Receiver,
-		Amount:    tx.Amount,
-		Timestamp: tx.Timestamp,
-		Signature: tx.Signature,
-	}
+	return tx.ToPb()
 }
 
 func ConvertToBlockchainTransaction(tx *pb.Transaction) *blockchain.Transaction {
-	return &blockchain.Transaction{
-		Sender:    tx.Sender,
-		Receiver:  tx.Receiver,
-		Amount:    tx.Amount,
-		Timestamp: tx.Timestamp,
-		Signature: tx.Signature,
-	}
+	return blockchain.TransactionFromPb(tx)
 }
 
 func ConvertToBlockchainBlock(block *pb.Block) *blockchain.Block {
@@ -37,6 +25,13 @@ func ConvertToBlockchainBlock(block *pb.Block) *blockchain.Block {
 		PreviousBlockHash: block.PreviousBlockHash,
 		CurrentBlockHash:  block.CurrentBlockHash,
 		Height:            block.Height,
+		ProposerId:        block.ProposerId,
+		ProposerSignature: block.ProposerSignature,
+		Round:             block.Round,
+		Timestamp:         block.Timestamp,
+		Nonce:             block.Nonce,
+		Difficulty:        block.Difficulty,
+		Evidence:          block.Evidence,
 	}
 }
 
@@ -52,5 +47,12 @@ func ConvertToPbBlock(block *blockchain.Block) *pb.Block {
 		PreviousBlockHash: block.PreviousBlockHash,
 		CurrentBlockHash:  block.CurrentBlockHash,
 		Height:            block.Height,
+		ProposerId:        block.ProposerId,
+		ProposerSignature: block.ProposerSignature,
+		Round:             block.Round,
+		Timestamp:         block.Timestamp,
+		Nonce:             block.Nonce,
+		Difficulty:        block.Difficulty,
+		Evidence:          block.Evidence,
 	}
 }
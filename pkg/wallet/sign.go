@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"fmt"
@@ -9,20 +10,42 @@ import (
 )
 
 func VerifyTransaction(tx *blockchain.Transaction, pubKey *ecdsa.PublicKey) bool {
-	txHash := tx.Hash()
-	// Assume signature is r and s concatenated, parse them back to big.Int
-	r := new(big.Int).SetBytes(tx.Signature[:len(tx.Signature)/2])
-	s := new(big.Int).SetBytes(tx.Signature[len(tx.Signature)/2:])
-	return ecdsa.Verify(pubKey, txHash, r, s)
+	return VerifyHash(tx.Hash(), tx.Signature, pubKey)
 }
 
 func SignTransaction(tx *blockchain.Transaction, privKey *ecdsa.PrivateKey) error {
-	txHash := tx.Hash()
-	r, s, err := ecdsa.Sign(rand.Reader, privKey, txHash)
+	signature, err := SignHash(tx.Hash(), privKey)
 	if err != nil {
 		return fmt.Errorf("failed to sign transaction: %w", err)
 	}
-	// Store R and S as a concatenated byte slice
-	tx.Signature = append(r.Bytes(), s.Bytes()...)
+	tx.Signature = signature
 	return nil
 }
+
+// SignHash returns R and S concatenated, each padded to 32 bytes
+func SignHash(hash []byte, privKey *ecdsa.PrivateKey) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature, nil
+}
+
+func VerifyHash(hash []byte, signature []byte, pubKey *ecdsa.PublicKey) bool {
+	if len(signature) == 0 || pubKey == nil || pubKey.X == nil {
+		return false
+	}
+	// Assume signature is r and s concatenated, parse them back to big.Int
+	r := new(big.Int).SetBytes(signature[:len(signature)/2])
+	s := new(big.Int).SetBytes(signature[len(signature)/2:])
+	return ecdsa.Verify(pubKey, hash, r, s)
+}
+
+// IsSenderPublicKey checks that the public key belongs to the sender address of the transaction
+func IsSenderPublicKey(tx *blockchain.Transaction, pubKey *ecdsa.PublicKey) bool {
+	return bytes.Equal(PublicKeyToAddress(pubKey), tx.Sender)
+}